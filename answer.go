@@ -0,0 +1,392 @@
+package gotoon
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// AnswerError lists the problems that kept ParseAnswer from using a model's
+// reply. Its RetryPrompt can be sent back to the model as is.
+type AnswerError struct {
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *AnswerError) Error() string {
+	return "gotoon: invalid answer: " + strings.Join(e.Problems, "; ")
+}
+
+// RetryPrompt asks the model to correct its answer.
+func (e *AnswerError) RetryPrompt() string {
+	var b strings.Builder
+	b.WriteString("Your answer could not be used:\n")
+	for _, problem := range e.Problems {
+		b.WriteString("- " + problem + "\n")
+	}
+	b.WriteString("Reply again with only the corrected TOON block in a ```toon fence.")
+	return b.String()
+}
+
+// AnswerInstructions returns a prompt snippet that asks a model to answer in
+// TOON shaped like v, which is a struct, a slice of structs, or a pointer to
+// either; only its type is used. A slice becomes a table with one typed column
+// per field, nested structs become dotted columns, and other fields hold JSON.
+// A struct becomes key: value lines. Field names follow toon tags, then json
+// tags, as in Unmarshal, and fields marked omitempty or held by pointers are
+// optional.
+func AnswerInstructions(v any) string {
+	t := answerType(v)
+
+	var b strings.Builder
+	b.WriteString("Answer with a single TOON block in a ```toon fence, shaped like this:\n\n```toon\n")
+
+	var columns []answerColumn
+	if t.Kind() == reflect.Slice && isAnswerStruct(t.Elem()) {
+		columns = answerColumns(t.Elem(), "", true)
+		writeAnswerTable(&b, columns, 0)
+	} else if isAnswerStruct(t) {
+		columns = writeAnswerObject(&b, t, 0)
+	} else {
+		fmt.Fprintf(&b, "%s\n", answerPlaceholder(t))
+	}
+	b.WriteString("```\n")
+
+	notes := []string{}
+	if strings.Contains(b.String(), "[N]") {
+		notes = append(notes, "Replace N with the number of rows and write one row per line, values in column order.",
+			`Escape commas inside values as \,.`)
+	}
+	var optional []string
+	hasTime, hasJSON := false, false
+	for _, column := range columns {
+		if !column.required {
+			optional = append(optional, column.path)
+		}
+		hasTime = hasTime || column.kind == timeColumnType
+		hasJSON = hasJSON || column.kind == jsonColumnType
+	}
+	if len(optional) > 0 {
+		notes = append(notes, "Leave optional values ("+strings.Join(optional, ", ")+") empty when unknown.")
+	}
+	if hasTime {
+		notes = append(notes, "Write times in RFC 3339, e.g. 2024-01-02T15:04:05Z.")
+	}
+	if hasJSON {
+		notes = append(notes, "json values are JSON arrays or objects on one line.")
+	}
+	for _, note := range notes {
+		b.WriteString("\n- " + note)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// ParseAnswer extracts the TOON block from a model's free-form reply and
+// stores it in v, which must be a pointer to the type given to
+// AnswerInstructions. The block is taken from a ```toon fence, any other fence,
+// or the reply's TOON-looking lines. It is decoded in strict mode with the
+// field types as type hints, so typed columns, "key: value" lines and row
+// counts are checked, required fields must be present and unknown fields are
+// rejected. Values are stored as Unmarshal stores them.
+// Problems are returned as an *AnswerError.
+func ParseAnswer(reply string, v any) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return errors.New("gotoon: ParseAnswer needs a non-nil pointer")
+	}
+	t := target.Elem().Type()
+	list := t.Kind() == reflect.Slice && isAnswerStruct(t.Elem())
+
+	keys := []string{"items"}
+	if isAnswerStruct(t) {
+		keys = nil
+		for _, field := range answerFields(t) {
+			keys = append(keys, field.name)
+		}
+	}
+
+	block, ok := extractTOON(reply, keys)
+	if !ok {
+		return &AnswerError{Problems: []string{"no TOON block found"}}
+	}
+
+	config := DefaultConfig()
+	config.Strict = true
+	decoded, err := NewDecoder(config).withTypeHints(t).Decode(block)
+	if err != nil {
+		return &AnswerError{Problems: []string{err.Error()}}
+	}
+
+	var problems []string
+	if !list {
+		if isAnswerStruct(t) {
+			checkAnswer(decoded, t, "", "", &problems)
+		}
+		if len(problems) == 0 {
+			if err := unmarshalValue(target.Elem(), decoded, ""); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if len(problems) > 0 {
+			return &AnswerError{Problems: problems}
+		}
+		return nil
+	}
+
+	items, ok := decoded["items"].([]any)
+	if !ok {
+		return &AnswerError{Problems: []string{"expected a table named items"}}
+	}
+	rows := reflect.MakeSlice(t, len(items), len(items))
+	for i, item := range items {
+		at := fmt.Sprintf("row %d: ", i+1)
+		row, _ := item.(map[string]any)
+		before := len(problems)
+		checkAnswer(row, t.Elem(), "", at, &problems)
+		if len(problems) > before {
+			continue
+		}
+		if err := unmarshalValue(rows.Index(i), row, ""); err != nil {
+			problems = append(problems, at+err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return &AnswerError{Problems: problems}
+	}
+	target.Elem().Set(rows)
+	return nil
+}
+
+// answerField is a struct field as named in TOON.
+type answerField struct {
+	name     string
+	typ      reflect.Type
+	required bool
+}
+
+// answerColumn is a leaf value of an answer: a dotted path and its column type.
+type answerColumn struct {
+	path     string
+	kind     string
+	required bool
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// answerType returns the type of v without pointers.
+func answerType(v any) reflect.Type {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return reflect.TypeOf((*any)(nil)).Elem()
+	}
+	return t
+}
+
+// isAnswerStruct reports whether t is a struct written as an object. Structs
+// that unmarshal themselves are single values.
+func isAnswerStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	return !isUnmarshaler(t)
+}
+
+// answerFields lists the fields of a struct as Unmarshal matches them, with
+// pointers removed from their types. Fields held by pointers or marked
+// omitempty are optional.
+func answerFields(t reflect.Type) []answerField {
+	var fields []answerField
+	for _, field := range cachedStructFields(t) {
+		typ := t.FieldByIndex(field.index).Type
+		pointer := typ.Kind() == reflect.Pointer
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		fields = append(fields, answerField{name: field.name, typ: typ, required: !pointer && !field.omitEmpty})
+	}
+	return fields
+}
+
+// answerColumns flattens a struct into table columns.
+func answerColumns(t reflect.Type, prefix string, required bool) []answerColumn {
+	var columns []answerColumn
+	for _, field := range answerFields(t) {
+		path := joinPath(prefix, field.name)
+		if isAnswerStruct(field.typ) {
+			columns = append(columns, answerColumns(field.typ, path, required && field.required)...)
+			continue
+		}
+		columns = append(columns, answerColumn{path: path, kind: answerKind(field.typ), required: required && field.required})
+	}
+	return columns
+}
+
+// answerKind returns the column type of a Go type, or "" for any value.
+func answerKind(t reflect.Type) string {
+	if t == timeType {
+		return timeColumnType
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return intColumnType
+	case reflect.Float32, reflect.Float64:
+		return floatColumnType
+	case reflect.String:
+		return strColumnType
+	case reflect.Bool:
+		return boolColumnType
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		return jsonColumnType
+	default:
+		return ""
+	}
+}
+
+// answerPlaceholder describes the value expected for a Go type.
+func answerPlaceholder(t reflect.Type) string {
+	if kind := answerKind(t); kind != "" {
+		return "<" + kind + ">"
+	}
+	return "<value>"
+}
+
+// writeAnswerTable writes a table header and a placeholder row.
+func writeAnswerTable(b *strings.Builder, columns []answerColumn, depth int) {
+	indent := strings.Repeat("  ", depth)
+	header := make([]string, len(columns))
+	cells := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.path
+		if column.kind != "" {
+			header[i] += ":" + column.kind
+		}
+		cells[i] = "<" + column.path + ">"
+	}
+	fmt.Fprintf(b, "%sitems[N]{%s}:\n%s  %s\n", indent, strings.Join(header, ","), indent, strings.Join(cells, ","))
+}
+
+// writeAnswerObject writes key: value lines for a struct and returns the
+// columns of its scalar fields.
+func writeAnswerObject(b *strings.Builder, t reflect.Type, depth int) []answerColumn {
+	indent := strings.Repeat("  ", depth)
+	var columns []answerColumn
+
+	for _, field := range answerFields(t) {
+		switch {
+		case isAnswerStruct(field.typ):
+			fmt.Fprintf(b, "%s%s:\n", indent, field.name)
+			for _, column := range writeAnswerObject(b, field.typ, depth+1) {
+				column.path = joinPath(field.name, column.path)
+				column.required = column.required && field.required
+				columns = append(columns, column)
+			}
+		case field.typ.Kind() == reflect.Slice && isAnswerStruct(field.typ.Elem()):
+			fmt.Fprintf(b, "%s%s:\n", indent, field.name)
+			writeAnswerTable(b, answerColumns(field.typ.Elem(), "", true), depth+1)
+		case field.typ.Kind() == reflect.Slice && field.typ.Elem().Kind() != reflect.Uint8:
+			fmt.Fprintf(b, "%s%s:\n%s  - %s\n", indent, field.name, indent, answerPlaceholder(field.typ.Elem()))
+		default:
+			fmt.Fprintf(b, "%s%s: %s\n", indent, field.name, answerPlaceholder(field.typ))
+			columns = append(columns, answerColumn{path: field.name, kind: answerKind(field.typ), required: field.required})
+		}
+	}
+	return columns
+}
+
+// checkAnswer reports required fields of t missing from obj and keys of obj
+// that are not fields of t. prefix is the
+// dotted path of obj and at locates it for messages, e.g. "row 2: ".
+func checkAnswer(obj map[string]any, t reflect.Type, prefix, at string, problems *[]string) {
+	fields := answerFields(t)
+	for _, key := range sortedKeys(obj) {
+		known := false
+		for _, field := range fields {
+			known = known || field.name == key
+		}
+		if !known {
+			*problems = append(*problems, fmt.Sprintf("%sunknown field %q", at, joinPath(prefix, key)))
+		}
+	}
+
+	for _, field := range fields {
+		path := joinPath(prefix, field.name)
+		value := obj[field.name]
+
+		switch {
+		case isAnswerStruct(field.typ):
+			nested, _ := value.(map[string]any)
+			if nested != nil || field.required {
+				checkAnswer(nested, field.typ, path, at, problems)
+			}
+		case value == nil:
+			if field.required {
+				*problems = append(*problems, at+"missing "+path)
+			}
+		case field.typ.Kind() == reflect.Slice && isAnswerStruct(field.typ.Elem()):
+			items, _ := value.([]any)
+			for i, item := range items {
+				row, _ := item.(map[string]any)
+				checkAnswer(row, field.typ.Elem(), "", fmt.Sprintf("%s%s row %d: ", at, path, i+1), problems)
+			}
+		}
+	}
+}
+
+// extractTOON finds the TOON block of a reply: the first ```toon fence, else
+// the first fence, else the lines from the first one starting with one of
+// keys up to the next line that is neither indented nor another key.
+func extractTOON(reply string, keys []string) (string, bool) {
+	lines := strings.Split(strings.ReplaceAll(reply, "\r\n", "\n"), "\n")
+
+	for _, lang := range []string{"toon", ""} {
+		for i, line := range lines {
+			fence := strings.TrimSpace(line)
+			if !strings.HasPrefix(fence, "```") || (lang != "" && strings.TrimSpace(fence[3:]) != lang) {
+				continue
+			}
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == "```" {
+					return strings.Join(lines[i+1:j], "\n"), true
+				}
+			}
+		}
+	}
+
+	startsWithKey := func(line string) bool {
+		for _, key := range keys {
+			if rest, ok := strings.CutPrefix(line, key); ok && (strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "[")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	start := -1
+	for i, line := range lines {
+		if start < 0 {
+			if startsWithKey(line) {
+				start = i
+			}
+			continue
+		}
+		if line != "" && lineIndent(line) == 0 && !startsWithKey(line) {
+			return strings.TrimRight(strings.Join(lines[start:i], "\n"), "\n"), true
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+	return strings.TrimRight(strings.Join(lines[start:], "\n"), "\n"), true
+}
+
+// lineIndent returns the number of leading spaces of line.
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,186 @@
+package gotoon
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type answerCustomer struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+type answerOrder struct {
+	ID       int            `json:"id"`
+	Customer answerCustomer `json:"customer"`
+	Total    float64        `json:"total"`
+	Shipped  *time.Time     `json:"shipped"`
+	Tags     []string       `json:"tags,omitempty"`
+}
+
+type answerSummary struct {
+	Title  string        `json:"title"`
+	Count  int           `json:"count"`
+	Orders []answerOrder `json:"orders"`
+	Labels []string      `json:"labels,omitempty"`
+}
+
+func TestAnswerInstructionsForList(t *testing.T) {
+	instructions := AnswerInstructions([]answerOrder{})
+
+	for _, want := range []string{
+		"```toon\nitems[N]{id:int,customer.name:str,customer.email:str,total:float,shipped:time,tags:json}:\n" +
+			"  <id>,<customer.name>,<customer.email>,<total>,<shipped>,<tags>\n```",
+		"Replace N with the number of rows",
+		"Leave optional values (customer.email, shipped, tags) empty when unknown.",
+		"RFC 3339",
+		"json values are JSON arrays",
+	} {
+		if !strings.Contains(instructions, want) {
+			t.Errorf("Expected %q in instructions:\n%s", want, instructions)
+		}
+	}
+}
+
+func TestAnswerInstructionsForObject(t *testing.T) {
+	instructions := AnswerInstructions((*answerSummary)(nil))
+
+	want := "```toon\ntitle: <str>\ncount: <int>\norders:\n  items[N]{id:int,customer.name:str,customer.email:str,total:float,shipped:time,tags:json}:\n" +
+		"    <id>,<customer.name>,<customer.email>,<total>,<shipped>,<tags>\nlabels:\n  - <str>\n```"
+	if !strings.Contains(instructions, want) {
+		t.Errorf("Expected %q in instructions:\n%s", want, instructions)
+	}
+}
+
+func TestParseAnswer(t *testing.T) {
+	reply := "Here are the orders you asked for:\n\n```toon\n" +
+		"items[2]{id:int,customer.name:str,customer.email:str,total:float,shipped:time,tags:json}:\n" +
+		"  1,Alice,,9.5,2024-01-02T10:00:00Z,[\"gift\"]\n" +
+		"  2,Bob\\, Jr,bob@example.com,12,,\n```\n\nLet me know if you need more."
+
+	var orders []answerOrder
+	if err := ParseAnswer(reply, &orders); err != nil {
+		t.Fatalf("ParseAnswer failed: %v", err)
+	}
+
+	shipped := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	expected := []answerOrder{
+		{ID: 1, Customer: answerCustomer{Name: "Alice"}, Total: 9.5, Shipped: &shipped, Tags: []string{"gift"}},
+		{ID: 2, Customer: answerCustomer{Name: "Bob, Jr", Email: "bob@example.com"}, Total: 12},
+	}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("Unexpected orders.\nExpected: %+v\nGot:      %+v", expected, orders)
+	}
+}
+
+func TestParseAnswerWithoutFence(t *testing.T) {
+	reply := "Sure.\ntitle: Weekly\ncount: 1\norders:\n  items[1]{id,customer.name,total}:\n    7,Carol,3\nThat's all."
+
+	var summary answerSummary
+	if err := ParseAnswer(reply, &summary); err != nil {
+		t.Fatalf("ParseAnswer failed: %v", err)
+	}
+	if summary.Title != "Weekly" || len(summary.Orders) != 1 || summary.Orders[0].Customer.Name != "Carol" {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+}
+
+func TestParseAnswerUsesToonTagsAndUnmarshalers(t *testing.T) {
+	type invoice struct {
+		Number string `toon:"no" json:"number"`
+		Amount money  `toon:"amount"`
+	}
+
+	instructions := AnswerInstructions(invoice{})
+	if !strings.Contains(instructions, "no: <str>\namount: <") {
+		t.Errorf("Expected toon tag names in instructions:\n%s", instructions)
+	}
+
+	var got invoice
+	if err := ParseAnswer("```toon\nno: A-1\namount: 950 EUR\n```", &got); err != nil {
+		t.Fatalf("ParseAnswer failed: %v", err)
+	}
+	expected := invoice{Number: "A-1", Amount: money{Cents: 950, Currency: "EUR"}}
+	if got != expected {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+
+	err := ParseAnswer("```toon\nnumber: A-1\namount: 950 EUR\n```", &got)
+	var answerErr *AnswerError
+	if !errors.As(err, &answerErr) || !strings.Contains(answerErr.Error(), `unknown field "number"`) {
+		t.Errorf("Expected json tag name to be rejected, got %v", err)
+	}
+}
+
+func TestParseAnswerUsesFieldTypes(t *testing.T) {
+	type address struct {
+		Zip   string `json:"zip"`
+		Floor int    `json:"floor"`
+	}
+
+	var got address
+	if err := ParseAnswer("```toon\nzip: 02134\nfloor: 3\n```", &got); err != nil {
+		t.Fatalf("ParseAnswer failed: %v", err)
+	}
+	if got != (address{Zip: "02134", Floor: 3}) {
+		t.Errorf("Unexpected address: %+v", got)
+	}
+
+	err := ParseAnswer("```toon\nzip: 02134\nfloor: 3.5\n```", &got)
+	var answerErr *AnswerError
+	if !errors.As(err, &answerErr) || !strings.Contains(answerErr.Error(), "floor: cannot store float64 in int") {
+		t.Errorf("Expected a type problem for floor, got %v", err)
+	}
+}
+
+func TestParseAnswerProblems(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  []string
+	}{
+		{"no block", "I don't know.", []string{"no TOON block found"}},
+		{"mistyped cell", "```toon\nitems[1]{id:int,customer.name:str,total:float}:\n  x,Alice,1\n```",
+			[]string{"line 2, column 3"}},
+		{"row count", "```toon\nitems[3]{id,customer.name,total}:\n  1,Alice,1\n```",
+			[]string{"declares 3 rows, found 1"}},
+		{"missing fields", "```toon\nitems[2]{id,customer.name,total}:\n  1,,1\n  2,Bob,\n```",
+			[]string{"row 1: missing customer.name", "row 2: missing total"}},
+		{"unknown field", "```toon\nitems[1]{id,customer.name,total,discount}:\n  1,Alice,1,5\n```",
+			[]string{`unknown field "discount"`}},
+		{"wrong type", "```toon\nitems[1]{id,customer.name,total}:\n  1,Alice,free\n```",
+			[]string{"row 1: total: cannot store string in float64"}},
+	}
+
+	for _, test := range tests {
+		var orders []answerOrder
+		err := ParseAnswer(test.reply, &orders)
+
+		var answerErr *AnswerError
+		if !errors.As(err, &answerErr) {
+			t.Errorf("%s: expected an AnswerError, got %v", test.name, err)
+			continue
+		}
+		if len(answerErr.Problems) != len(test.want) {
+			t.Errorf("%s: expected %d problems, got %q", test.name, len(test.want), answerErr.Problems)
+			continue
+		}
+		for i, want := range test.want {
+			if !strings.Contains(answerErr.Problems[i], want) {
+				t.Errorf("%s: expected %q, got %q", test.name, want, answerErr.Problems[i])
+			}
+		}
+	}
+}
+
+func TestAnswerErrorRetryPrompt(t *testing.T) {
+	err := &AnswerError{Problems: []string{"row 1: missing total"}}
+
+	expected := "Your answer could not be used:\n- row 1: missing total\nReply again with only the corrected TOON block in a ```toon fence."
+	if err.RetryPrompt() != expected {
+		t.Errorf("Unexpected retry prompt:\n%s", err.RetryPrompt())
+	}
+}
@@ -0,0 +1,180 @@
+// Package ast declares the syntax tree of TOON documents, along with a parser
+// that keeps what decoding throws away (table headers, declared row counts,
+// raw cell text, key order and comments) and a printer that writes it back.
+//
+// Tools such as formatters, linters and editors can parse a document, inspect
+// or rewrite the tree and print it without losing information.
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pos is a 1-based line and column in the source.
+type Pos struct {
+	Line   int
+	Column int
+}
+
+// String returns the position as "line:column".
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Node is implemented by all syntax tree nodes.
+type Node interface {
+	Position() Pos
+}
+
+// Document is the root of a parsed TOON source. Nodes holds the top-level
+// block: object entries, list items or a single scalar.
+type Document struct {
+	Nodes []Node
+}
+
+// Position returns the start of the document.
+func (d *Document) Position() Pos { return Pos{Line: 1, Column: 1} }
+
+// KeyValue is a "key: value" line. Value holds the raw, still escaped, text.
+type KeyValue struct {
+	Pos   Pos
+	Key   string
+	Value string
+}
+
+// Position returns the position of the key.
+func (n *KeyValue) Position() Pos { return n.Pos }
+
+// Object is a "key:" line followed by an indented block. Body is nil when the
+// key has no nested lines.
+type Object struct {
+	Pos  Pos
+	Key  string
+	Body []Node
+}
+
+// Position returns the position of the key.
+func (n *Object) Position() Pos { return n.Pos }
+
+// ListItem is a "- " prefixed list item and the block it introduces.
+type ListItem struct {
+	Pos  Pos
+	Body []Node
+}
+
+// Position returns the position of the dash.
+func (n *ListItem) Position() Pos { return n.Pos }
+
+// Scalar is a bare value, such as the content of "- 42". Value holds the raw,
+// still escaped, text.
+type Scalar struct {
+	Pos   Pos
+	Value string
+}
+
+// Position returns the position of the value.
+func (n *Scalar) Position() Pos { return n.Pos }
+
+// Comment is a "# text" line. Text excludes the "#" and one following space.
+type Comment struct {
+	Pos  Pos
+	Text string
+}
+
+// Position returns the position of the "#".
+func (n *Comment) Position() Pos { return n.Pos }
+
+// Table is a table header such as "items[2]{id,name}@{currency=EUR}:" with its
+// dictionaries, reference tables and rows. Reference tables are named after
+// their column, e.g. "*customer".
+type Table struct {
+	Pos          Pos
+	Name         string
+	Count        int
+	Columns      []Column
+	Attributes   []*Attribute
+	Dictionaries []*Dictionary
+	References   []*Table
+	Rows         []*Row
+}
+
+// Position returns the position of the table header.
+func (n *Table) Position() Pos { return n.Pos }
+
+// Column is a column declared in a table header, e.g. "tags?:json".
+type Column struct {
+	Name     string
+	Optional bool
+	Type     string
+}
+
+// ParseColumn parses a column declaration into its name, optional marker and type.
+func ParseColumn(spec string) Column {
+	name, typ, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	return Column{
+		Name:     strings.TrimSuffix(name, optionalSuffix),
+		Optional: strings.HasSuffix(name, optionalSuffix),
+		Type:     typ,
+	}
+}
+
+// String returns the column declaration as written in a table header.
+func (c Column) String() string {
+	s := c.Name
+	if c.Optional {
+		s += optionalSuffix
+	}
+	if c.Type != "" {
+		s += ":" + c.Type
+	}
+	return s
+}
+
+// Attribute is a "key=value" entry of a table header. Value holds the raw,
+// still escaped, text.
+type Attribute struct {
+	Column Column
+	Value  string
+}
+
+// Dictionary is a "&column: value,value" line inside a table.
+type Dictionary struct {
+	Pos    Pos
+	Column string
+	Values []*Cell
+}
+
+// Position returns the position of the "&".
+func (n *Dictionary) Position() Pos { return n.Pos }
+
+// Row is a table row.
+type Row struct {
+	Pos   Pos
+	Cells []*Cell
+}
+
+// Position returns the position of the first cell.
+func (n *Row) Position() Pos { return n.Pos }
+
+// Cell is a single table or dictionary cell. Raw holds the text between the
+// separators, including surrounding spaces and escapes.
+type Cell struct {
+	Pos Pos
+	Raw string
+}
+
+// Position returns the position of the cell.
+func (n *Cell) Position() Pos { return n.Pos }
+
+// SyntaxError describes malformed TOON input.
+type SyntaxError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
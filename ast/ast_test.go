@@ -0,0 +1,151 @@
+package ast
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const sampleToon = `# exported orders
+count: 2
+orders:
+  items[2]{id,note?,tags:json}@{currency=EUR}:
+    &status: open,closed
+    *customer[1]{id,name}:
+      c1,Alice
+    1,a\, b,["x"]
+    2,,[]
+tags:
+  - a
+  # second tag
+  - #hashtag`
+
+func TestParseBuildsTree(t *testing.T) {
+	doc, err := Parse(sampleToon)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if len(doc.Nodes) != 4 {
+		t.Fatalf("Expected 4 top-level nodes, got %d", len(doc.Nodes))
+	}
+
+	comment, ok := doc.Nodes[0].(*Comment)
+	if !ok || comment.Text != "exported orders" {
+		t.Errorf("Expected leading comment, got %#v", doc.Nodes[0])
+	}
+
+	count, ok := doc.Nodes[1].(*KeyValue)
+	if !ok || count.Key != "count" || count.Value != "2" || count.Pos != (Pos{Line: 2, Column: 1}) {
+		t.Errorf("Unexpected key-value node: %#v", doc.Nodes[1])
+	}
+
+	orders := doc.Nodes[2].(*Object)
+	table := orders.Body[0].(*Table)
+	if table.Name != "items" || table.Count != 2 || table.Pos != (Pos{Line: 4, Column: 3}) {
+		t.Errorf("Unexpected table header: %+v", table)
+	}
+
+	expectedColumns := []Column{{Name: "id"}, {Name: "note", Optional: true}, {Name: "tags", Type: "json"}}
+	for i, col := range expectedColumns {
+		if table.Columns[i] != col {
+			t.Errorf("Column %d: expected %+v, got %+v", i, col, table.Columns[i])
+		}
+	}
+
+	if len(table.Attributes) != 1 || table.Attributes[0].Column.Name != "currency" || table.Attributes[0].Value != "EUR" {
+		t.Errorf("Unexpected attributes: %+v", table.Attributes)
+	}
+
+	if len(table.Dictionaries) != 1 || table.Dictionaries[0].Column != "status" || len(table.Dictionaries[0].Values) != 2 {
+		t.Errorf("Unexpected dictionaries: %+v", table.Dictionaries)
+	}
+
+	if len(table.References) != 1 || table.References[0].Name != "*customer" || len(table.References[0].Rows) != 1 {
+		t.Errorf("Unexpected references: %+v", table.References)
+	}
+
+	row := table.Rows[0]
+	if len(row.Cells) != 3 || row.Cells[1].Raw != `a\, b` {
+		t.Errorf("Expected escaped comma to stay in its cell, got %+v", row.Cells)
+	}
+	if row.Cells[2].Pos != (Pos{Line: 8, Column: 13}) {
+		t.Errorf("Expected third cell at 8:13, got %v", row.Cells[2].Pos)
+	}
+
+	tags := doc.Nodes[3].(*Object)
+	if len(tags.Body) != 3 {
+		t.Fatalf("Expected 2 list items and a comment, got %d nodes", len(tags.Body))
+	}
+	if _, ok := tags.Body[1].(*Comment); !ok {
+		t.Errorf("Expected comment between list items, got %#v", tags.Body[1])
+	}
+	if scalar := tags.Body[2].(*ListItem).Body[0].(*Scalar); scalar.Value != "#hashtag" {
+		t.Errorf("Expected list item scalar to keep its \"#\", got %q", scalar.Value)
+	}
+}
+
+func TestPrinterRoundTrip(t *testing.T) {
+	doc, err := Parse(sampleToon)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	printer := &Printer{}
+	if printed := printer.Print(doc); printed != sampleToon {
+		t.Errorf("Printed document differs.\nExpected:\n%s\nGot:\n%s", sampleToon, printed)
+	}
+
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, doc); err != nil || buf.String() != sampleToon {
+		t.Errorf("Fprint differs from Print: %v", err)
+	}
+}
+
+func TestPrinterNormalizesLayout(t *testing.T) {
+	doc, err := Parse("user:\n    name:   Alice\n    roles:\n        -   admin\nitems[1]{ id , name }:\n      1 , Bob")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := "user:\n    name: Alice\n    roles:\n        - admin\nitems[1]{id,name}:\n    1,Bob"
+	printer := &Printer{Indent: 4}
+	if printed := printer.Print(doc); printed != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, printed)
+	}
+}
+
+func TestPrinterUsesRowCount(t *testing.T) {
+	doc, _ := Parse("items[1]{id}:\n  1")
+	table := doc.Nodes[0].(*Table)
+	table.Rows = append(table.Rows, &Row{Cells: []*Cell{{Raw: "2"}}})
+
+	printer := &Printer{}
+	if printed := printer.Print(doc); printed != "items[2]{id}:\n  1\n  2" {
+		t.Errorf("Expected header to count added row, got:\n%s", printed)
+	}
+}
+
+func TestParseReportsFirstError(t *testing.T) {
+	doc, err := Parse("name: Alice\nitems[2]{id,name}:\n  1,Alice,extra\n  2")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected *SyntaxError, got: %v", err)
+	}
+	if syntaxErr.Line != 3 || syntaxErr.Column != 3 {
+		t.Errorf("Expected error at 3:3, got %v", syntaxErr)
+	}
+
+	if doc == nil || len(doc.Nodes) != 2 {
+		t.Errorf("Expected best-effort tree alongside the error, got %+v", doc)
+	}
+}
+
+func TestParseColumn(t *testing.T) {
+	for _, spec := range []string{"id", "note?", "tags:json", "meta?:json"} {
+		if got := ParseColumn(" " + spec + " ").String(); got != spec {
+			t.Errorf("Expected %q to round-trip, got %q", spec, got)
+		}
+	}
+}
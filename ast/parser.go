@@ -0,0 +1,406 @@
+package ast
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// referencePrefix starts the name of a reference table, e.g. "*customer".
+	referencePrefix = "*"
+
+	// optionalSuffix marks a column whose cells may be absent, e.g. "note?".
+	optionalSuffix = "?"
+
+	// commentPrefix starts a comment line.
+	commentPrefix = "#"
+)
+
+// tableHeaderPattern matches table headers such as "items[2]{id,name}:" and
+// reference table headers such as "*customer[2]{id,name}:".
+var tableHeaderPattern = regexp.MustCompile(`^(items|\*[^\s\[\]]+)\[(\d+)\]\{([^\}]*)\}(?:@\{(.*)\})?:$`)
+
+// dictionaryLinePattern matches a table dictionary line such as "&status: open,closed".
+var dictionaryLinePattern = regexp.MustCompile(`^&([^\s:,\\]+): (.*)$`)
+
+// Parse parses a TOON document. Parsing always completes: when the source is
+// malformed the best-effort tree is returned along with a *SyntaxError for the
+// first problem found.
+func Parse(src string) (*Document, error) {
+	p := &parser{lines: strings.Split(src, "\n")}
+
+	nodes, next := p.parseBlock(0, -1)
+	if len(nodes) == 0 {
+		nodes, next = p.parseComments(next, len(p.lines))
+	}
+	if next = nextContentLine(p.lines, next); next < len(p.lines) {
+		p.fail(next, "unexpected content after top-level block")
+	}
+
+	doc := &Document{Nodes: nodes}
+	if p.err != nil {
+		return doc, p.err
+	}
+	return doc, nil
+}
+
+// parser builds a syntax tree from TOON lines and records the first error.
+type parser struct {
+	lines []string
+	err   *SyntaxError
+}
+
+// fail records a syntax error at the start of the given line index.
+func (p *parser) fail(line int, format string, args ...any) {
+	p.failAt(p.pos(line, lineIndent(p.lines[line])), format, args...)
+}
+
+// failAt records a syntax error at the given position. Only the first error is kept.
+func (p *parser) failAt(at Pos, format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	p.err = &SyntaxError{Line: at.Line, Column: at.Column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// pos converts a zero-based line index and column offset into a Pos.
+func (p *parser) pos(line, column int) Pos {
+	return Pos{Line: line + 1, Column: column + 1}
+}
+
+// parseBlock parses the lines from start that are indented deeper than
+// parentIndent. Depending on its first non-comment line the block is a list,
+// an object (which may consist of a single table) or a scalar. It returns the
+// nodes and the index of the first line after the block.
+func (p *parser) parseBlock(start, parentIndent int) ([]Node, int) {
+	i := nextNodeLine(p.lines, start)
+	if i >= len(p.lines) || lineIndent(p.lines[i]) <= parentIndent {
+		return nil, start
+	}
+
+	indent := lineIndent(p.lines[i])
+	content := strings.TrimSpace(p.lines[i])
+
+	if isListItem(content) {
+		return p.parseList(start, indent)
+	}
+
+	if _, _, ok := splitKeyValue(content); ok || isKeyLine(content) || tableHeaderPattern.MatchString(content) {
+		return p.parseObject(start, indent)
+	}
+
+	nodes, _ := p.parseComments(start, i)
+	return append(nodes, &Scalar{Pos: p.pos(i, indent), Value: content}), i + 1
+}
+
+// parseComments parses the comment lines between start and end, which must
+// only hold blank and comment lines.
+func (p *parser) parseComments(start, end int) ([]Node, int) {
+	var nodes []Node
+	for i := start; i < end; i++ {
+		if comment := p.parseComment(i); comment != nil {
+			nodes = append(nodes, comment)
+		}
+	}
+	return nodes, end
+}
+
+// parseComment returns the comment on the given line, or nil if there is none.
+func (p *parser) parseComment(i int) *Comment {
+	content := strings.TrimSpace(p.lines[i])
+	if !isComment(content) {
+		return nil
+	}
+	text := strings.TrimPrefix(strings.TrimPrefix(content, commentPrefix), " ")
+	return &Comment{Pos: p.pos(i, lineIndent(p.lines[i])), Text: text}
+}
+
+// belongsToBlock reports whether the comment on line i belongs to the block
+// at the given indent. A comment belongs to the block of the line it precedes,
+// or to the block matching its own indent at the end of the source.
+func (p *parser) belongsToBlock(i, indent int) bool {
+	if next := nextNodeLine(p.lines, i); next < len(p.lines) {
+		return lineIndent(p.lines[next]) >= indent
+	}
+	return lineIndent(p.lines[i]) >= indent
+}
+
+// parseObject parses "key: value", "key:", table and comment lines at the given indent.
+func (p *parser) parseObject(start, indent int) ([]Node, int) {
+	var nodes []Node
+
+	i := start
+	for i < len(p.lines) {
+		content := strings.TrimSpace(p.lines[i])
+		if content == "" {
+			i++
+			continue
+		}
+
+		if isComment(content) {
+			if !p.belongsToBlock(i, indent) {
+				break
+			}
+			nodes = append(nodes, p.parseComment(i))
+			i++
+			continue
+		}
+
+		if lineIndent(p.lines[i]) < indent {
+			break
+		}
+		if lineIndent(p.lines[i]) > indent {
+			p.fail(i, "unexpected indentation")
+			i++
+			continue
+		}
+
+		at := p.pos(i, indent)
+		if isListItem(content) {
+			p.fail(i, "unexpected list item inside an object")
+			break
+		}
+
+		if match := tableHeaderPattern.FindStringSubmatch(content); match != nil && match[1] == "items" {
+			var table *Table
+			table, i = p.parseTable(i, match)
+			nodes = append(nodes, table)
+			i++
+			continue
+		}
+
+		if key, value, ok := splitKeyValue(content); ok {
+			nodes = append(nodes, &KeyValue{Pos: at, Key: key, Value: value})
+			i++
+			continue
+		}
+
+		if isKeyLine(content) {
+			obj := &Object{Pos: at, Key: strings.TrimSuffix(content, ":")}
+			obj.Body, i = p.parseBlock(i+1, indent)
+			nodes = append(nodes, obj)
+			continue
+		}
+
+		p.fail(i, `expected "key: value", "key:" or a table header`)
+		i++
+	}
+
+	return nodes, i
+}
+
+// parseList parses "- " prefixed list items and comments at the given indent.
+// Each item is parsed as a block indented two spaces deeper than its dash.
+func (p *parser) parseList(start, indent int) ([]Node, int) {
+	var nodes []Node
+
+	i := start
+	for i < len(p.lines) {
+		content := strings.TrimSpace(p.lines[i])
+		if content == "" {
+			i++
+			continue
+		}
+
+		if isComment(content) {
+			if !p.belongsToBlock(i, indent) {
+				break
+			}
+			nodes = append(nodes, p.parseComment(i))
+			i++
+			continue
+		}
+
+		if lineIndent(p.lines[i]) < indent {
+			break
+		}
+		if lineIndent(p.lines[i]) > indent {
+			p.fail(i, "unexpected indentation")
+			i++
+			continue
+		}
+
+		if !isListItem(content) {
+			p.fail(i, `expected a "- " list item`)
+			break
+		}
+
+		item := &ListItem{Pos: p.pos(i, indent)}
+		rest := strings.TrimPrefix(strings.TrimPrefix(content, "-"), " ")
+		switch {
+		case rest == "":
+			item.Body, i = p.parseBlock(i+1, indent)
+		case isComment(rest):
+			// A scalar after the dash is never a comment.
+			item.Body = []Node{&Scalar{Pos: p.pos(i, indent+2), Value: rest}}
+			i++
+		default:
+			p.lines[i] = strings.Repeat(" ", indent+2) + rest
+			item.Body, i = p.parseBlock(i, indent)
+		}
+
+		nodes = append(nodes, item)
+	}
+
+	return nodes, i
+}
+
+// parseTable parses the table whose header is on p.lines[start] and returns it
+// along with the index of the last line belonging to the table.
+func (p *parser) parseTable(start int, match []string) (*Table, int) {
+	headerIndent := lineIndent(p.lines[start])
+	table := &Table{
+		Pos:  p.pos(start, headerIndent),
+		Name: match[1],
+	}
+	table.Count, _ = strconv.Atoi(match[2])
+
+	if match[3] != "" {
+		for _, spec := range strings.Split(match[3], ",") {
+			table.Columns = append(table.Columns, ParseColumn(spec))
+		}
+	}
+
+	if match[4] != "" {
+		for _, attr := range splitEscaped(match[4], ',') {
+			key, value, ok := strings.Cut(attr, "=")
+			if !ok {
+				continue
+			}
+			table.Attributes = append(table.Attributes, &Attribute{Column: ParseColumn(key), Value: value})
+		}
+	}
+
+	j := start + 1
+	for len(table.Rows) < table.Count && j < len(p.lines) {
+		content := strings.TrimSpace(p.lines[j])
+		line, indent := j, lineIndent(p.lines[j])
+		at := p.pos(line, indent)
+		j++
+
+		if content == "" {
+			continue
+		}
+
+		if indent <= headerIndent {
+			p.failAt(at, "table row must be indented under its header")
+		}
+
+		if dictMatch := dictionaryLinePattern.FindStringSubmatch(content); dictMatch != nil {
+			offset := indent + len(content) - len(dictMatch[2])
+			table.Dictionaries = append(table.Dictionaries, &Dictionary{
+				Pos:    at,
+				Column: dictMatch[1],
+				Values: p.splitCells(line, offset, dictMatch[2]),
+			})
+			continue
+		}
+
+		if refMatch := tableHeaderPattern.FindStringSubmatch(content); refMatch != nil && strings.HasPrefix(refMatch[1], referencePrefix) {
+			var ref *Table
+			ref, j = p.parseTable(line, refMatch)
+			table.References = append(table.References, ref)
+			j++
+			continue
+		}
+
+		row := &Row{Pos: at, Cells: p.splitCells(line, indent, content)}
+		if len(row.Cells) != len(table.Columns) {
+			p.failAt(at, "row has %d cells, expected %d", len(row.Cells), len(table.Columns))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	if len(table.Rows) < table.Count {
+		p.failAt(table.Pos, "table declares %d rows, found %d", table.Count, len(table.Rows))
+	}
+
+	return table, j - 1
+}
+
+// splitCells splits text, found at the given line index and column offset, into
+// cells on unescaped commas.
+func (p *parser) splitCells(line, offset int, text string) []*Cell {
+	parts := splitEscaped(text, ',')
+	cells := make([]*Cell, len(parts))
+	for i, part := range parts {
+		cells[i] = &Cell{Pos: p.pos(line, offset), Raw: part}
+		offset += len(part) + 1
+	}
+	return cells
+}
+
+// splitEscaped splits s on sep, ignoring separators preceded by a backslash.
+// Escape sequences are kept intact for later unescaping.
+func splitEscaped(s string, sep byte) []string {
+	parts := []string{}
+	start := 0
+	escaped := false
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// lineIndent returns the number of leading spaces in line.
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// nextContentLine returns the index of the first non-blank line at or after start.
+func nextContentLine(lines []string, start int) int {
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	return start
+}
+
+// nextNodeLine returns the index of the first line at or after start that is
+// neither blank nor a comment.
+func nextNodeLine(lines []string, start int) int {
+	for start < len(lines) {
+		content := strings.TrimSpace(lines[start])
+		if content != "" && !isComment(content) {
+			break
+		}
+		start++
+	}
+	return start
+}
+
+// isComment checks if content is a "#" comment line.
+func isComment(content string) bool {
+	return strings.HasPrefix(content, commentPrefix)
+}
+
+// isListItem checks if content is a "- " prefixed list item.
+func isListItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// isKeyLine checks if content opens a nested block, e.g. "user:".
+func isKeyLine(content string) bool {
+	return strings.HasSuffix(content, ":") && !strings.HasSuffix(content, "\\:") && !strings.Contains(content, ": ")
+}
+
+// splitKeyValue splits a "key: value" line on the first unescaped ": ".
+func splitKeyValue(content string) (string, string, bool) {
+	for i := 1; i+1 < len(content); i++ {
+		if content[i] == ':' && content[i+1] == ' ' && content[i-1] != '\\' {
+			return content[:i], content[i+2:], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,140 @@
+package ast
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Printer prints a syntax tree as TOON text. Raw values and cells are written
+// as they are, so printing a parsed document reproduces its content with
+// normalized indentation and spacing.
+type Printer struct {
+	// Indent is the number of spaces per nesting level. Zero means two.
+	Indent int
+}
+
+// Print returns the TOON text of doc.
+func (p *Printer) Print(doc *Document) string {
+	return strings.Join(p.block(doc.Nodes, 0), "\n")
+}
+
+// Fprint writes the TOON text of doc to w.
+func (p *Printer) Fprint(w io.Writer, doc *Document) error {
+	_, err := io.WriteString(w, p.Print(doc))
+	return err
+}
+
+// indent returns the indentation for the given depth.
+func (p *Printer) indent(depth int) string {
+	width := p.Indent
+	if width <= 0 {
+		width = 2
+	}
+	return strings.Repeat(" ", width*depth)
+}
+
+// block prints a block of nodes at the given depth.
+func (p *Printer) block(nodes []Node, depth int) []string {
+	indent := p.indent(depth)
+
+	var lines []string
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *KeyValue:
+			lines = append(lines, indent+n.Key+": "+strings.TrimSpace(n.Value))
+		case *Object:
+			lines = append(lines, indent+n.Key+":")
+			lines = append(lines, p.block(n.Body, depth+1)...)
+		case *ListItem:
+			lines = append(lines, p.listItem(n, depth)...)
+		case *Scalar:
+			lines = append(lines, indent+strings.TrimSpace(n.Value))
+		case *Comment:
+			lines = append(lines, indent+p.comment(n))
+		case *Table:
+			lines = append(lines, p.table(n, depth)...)
+		}
+	}
+
+	return lines
+}
+
+// comment prints a comment without indentation.
+func (p *Printer) comment(c *Comment) string {
+	if c.Text == "" {
+		return commentPrefix
+	}
+	return commentPrefix + " " + c.Text
+}
+
+// listItem prints a list item, placing the first line of its body after the
+// dash. Comments leading the body stay above the item.
+func (p *Printer) listItem(item *ListItem, depth int) []string {
+	indent := p.indent(depth)
+	inner := p.indent(depth + 1)
+
+	var lines []string
+	body := item.Body
+	for len(body) > 0 {
+		c, ok := body[0].(*Comment)
+		if !ok {
+			break
+		}
+		lines = append(lines, indent+p.comment(c))
+		body = body[1:]
+	}
+
+	printed := p.block(body, depth+1)
+	if len(printed) == 0 {
+		return append(lines, indent+"-")
+	}
+
+	printed[0] = indent + "- " + strings.TrimPrefix(printed[0], inner)
+	return append(lines, printed...)
+}
+
+// table prints a table header followed by its dictionaries, reference tables
+// and rows. The row count is always the number of rows in the tree.
+func (p *Printer) table(table *Table, depth int) []string {
+	indent := p.indent(depth)
+	inner := p.indent(depth + 1)
+
+	columns := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		columns[i] = col.String()
+	}
+
+	header := indent + table.Name + "[" + strconv.Itoa(len(table.Rows)) + "]{" + strings.Join(columns, ",") + "}"
+	if len(table.Attributes) > 0 {
+		attrs := make([]string, len(table.Attributes))
+		for i, attr := range table.Attributes {
+			attrs[i] = attr.Column.String() + "=" + strings.TrimSpace(attr.Value)
+		}
+		header += "@{" + strings.Join(attrs, ",") + "}"
+	}
+	lines := []string{header + ":"}
+
+	for _, dict := range table.Dictionaries {
+		lines = append(lines, inner+"&"+dict.Column+": "+joinCells(dict.Values))
+	}
+
+	for _, ref := range table.References {
+		lines = append(lines, p.table(ref, depth+1)...)
+	}
+
+	for _, row := range table.Rows {
+		lines = append(lines, inner+joinCells(row.Cells))
+	}
+
+	return lines
+}
+
+// joinCells joins the trimmed raw text of cells with commas.
+func joinCells(cells []*Cell) string {
+	raw := make([]string, len(cells))
+	for i, cell := range cells {
+		raw[i] = strings.TrimSpace(cell.Raw)
+	}
+	return strings.Join(raw, ",")
+}
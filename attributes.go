@@ -0,0 +1,88 @@
+package gotoon
+
+import "github.com/b92c/gotoon/ast"
+
+// hoistConstantColumns removes columns that hold the same non-empty value in every
+// row and returns them as "key=value" table attributes. At least one column is
+// always kept so rows remain visible.
+func hoistConstantColumns(columns []string, rows [][]any, cells [][]string) ([]string, [][]any, [][]string, []string) {
+	if len(rows) < 2 {
+		return columns, rows, cells, nil
+	}
+
+	keep := []int{}
+	attributes := []string{}
+	for col := range columns {
+		if !isConstantColumn(cells, col) || (col == len(columns)-1 && len(keep) == 0) {
+			keep = append(keep, col)
+			continue
+		}
+		attributes = append(attributes, columns[col]+"="+cells[0][col])
+	}
+
+	if len(attributes) == 0 {
+		return columns, rows, cells, nil
+	}
+
+	keptColumns := make([]string, len(keep))
+	for i, col := range keep {
+		keptColumns[i] = columns[col]
+	}
+
+	keptRows := make([][]any, len(rows))
+	keptCells := make([][]string, len(cells))
+	for i := range rows {
+		keptRows[i] = make([]any, len(keep))
+		keptCells[i] = make([]string, len(keep))
+		for j, col := range keep {
+			keptRows[i][j] = rows[i][col]
+			keptCells[i][j] = cells[i][col]
+		}
+	}
+
+	return keptColumns, keptRows, keptCells, attributes
+}
+
+// isConstantColumn reports whether every row has the same non-empty cell in col.
+func isConstantColumn(cells [][]string, col int) bool {
+	first := cells[0][col]
+	if first == "" {
+		return false
+	}
+
+	for _, row := range cells[1:] {
+		if row[col] != first {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAttributes parses the "key=value" attributes of a table header into
+// column names and values. Attributes without a declared type use the type
+// hint for their path below itemPath.
+func (d *Decoder) parseAttributes(attrs []*ast.Attribute, itemPath string) ([]string, []any) {
+	var keys []string
+	var values []any
+
+	for _, attr := range attrs {
+		keys = append(keys, attr.Column.Name)
+		typ := attr.Column.Type
+		if typ == "" {
+			typ = d.typeHint(joinPath(itemPath, attr.Column.Name))
+		}
+
+		switch {
+		case typ == jsonColumnType:
+			value, _ := decodeJSONCell(attr.Value)
+			values = append(values, value)
+		case isScalarColumnType(typ):
+			value, _ := d.parseTyped(attr.Value, typ)
+			values = append(values, value)
+		default:
+			values = append(values, d.parseValue(attr.Value))
+		}
+	}
+
+	return keys, values
+}
@@ -0,0 +1,109 @@
+package gotoon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHoistConstantColumns(t *testing.T) {
+	config := DefaultConfig()
+	config.HoistConstantColumns = true
+
+	data := []any{
+		map[string]any{"id": 1, "name": "Widget", "currency": "EUR", "tenant_id": 7},
+		map[string]any{"id": 2, "name": "Gadget", "currency": "EUR", "tenant_id": 7},
+		map[string]any{"id": 3, "name": "Gizmo", "currency": "EUR", "tenant_id": 7},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.HasPrefix(toon, "items[3]{id,name}@{currency=EUR,tenant_id=7}:") {
+		t.Errorf("Expected constant columns in header attributes, got: %s", toon)
+	}
+	if strings.Count(toon, "EUR") != 1 {
+		t.Errorf("Expected 'EUR' to be written once, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got: %d", len(items))
+	}
+	for i, item := range items {
+		obj := item.(map[string]any)
+		if obj["currency"] != "EUR" || obj["tenant_id"] != 7 {
+			t.Errorf("Row %d: expected hoisted values to be restored, got: %+v", i, obj)
+		}
+		if obj["id"] != i+1 {
+			t.Errorf("Row %d: expected id=%d, got: %v", i, i+1, obj["id"])
+		}
+	}
+}
+
+func TestHoistConstantNestedColumnsWithEscaping(t *testing.T) {
+	config := DefaultConfig()
+	config.HoistConstantColumns = true
+
+	data := map[string]any{
+		"bookings": []any{
+			map[string]any{"id": "a", "venue": map[string]any{"name": "Club X", "city": "Amsterdam, NL"}},
+			map[string]any{"id": "b", "venue": map[string]any{"name": "Club X", "city": "Amsterdam, NL"}},
+		},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.Contains(toon, "@{venue.city=Amsterdam\\, NL,venue.name=Club X}:") {
+		t.Errorf("Expected escaped nested attributes, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	bookings := decoded["bookings"].([]any)
+	venue := bookings[1].(map[string]any)["venue"].(map[string]any)
+	if venue["city"] != "Amsterdam, NL" || venue["name"] != "Club X" {
+		t.Errorf("Expected nested venue to be restored, got: %+v", venue)
+	}
+}
+
+func TestHoistKeepsAtLeastOneColumn(t *testing.T) {
+	config := DefaultConfig()
+	config.HoistConstantColumns = true
+
+	data := []any{
+		map[string]any{"status": "ok", "code": 200},
+		map[string]any{"status": "ok", "code": 200},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.HasPrefix(toon, "items[2]{status}@{code=200}:") {
+		t.Errorf("Expected the last column to stay in the table, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	if len(items) != 2 || items[1].(map[string]any)["status"] != "ok" {
+		t.Errorf("Expected both rows to round-trip, got: %+v", items)
+	}
+}
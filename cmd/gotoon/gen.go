@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/b92c/gotoon"
+)
+
+// runGen writes Go type definitions inferred from the input sample. Dotted
+// table columns such as "customer.name" become nested struct types.
+func runGen(c *command) error {
+	data, err := c.readData()
+	if err != nil {
+		return err
+	}
+
+	tags := splitList(c.tags)
+	if len(tags) == 0 {
+		return fmt.Errorf("-tags must name at least one struct tag key")
+	}
+
+	types := gotoon.InferSchema(data).GoStruct(c.typeName, tags...)
+
+	var b strings.Builder
+	b.WriteString("// Code generated by gotoon gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", c.pkg)
+	if strings.Contains(types, "time.Time") {
+		b.WriteString("import \"time\"\n\n")
+	}
+	b.WriteString(types)
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return fmt.Errorf("generated invalid Go source: %w", err)
+	}
+
+	_, err = c.stdout.Write(src)
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/b92c/gotoon"
+	"github.com/b92c/gotoon/yaml"
+)
+
+// readData reads the command input and parses it according to -from, or the
+// input file extension when -from is not set.
+func (c *command) readData() (any, error) {
+	src, err := c.input()
+	if err != nil {
+		return nil, err
+	}
+
+	switch format := c.inputFormat(); format {
+	case "", "json":
+		return parseJSON(src)
+	case "csv":
+		return gotoon.ReadCSV(bytes.NewReader(src), &gotoon.CSVOptions{Config: c.config()})
+	case "toon":
+		return c.parseTOON(src)
+	case "yaml", "yml":
+		return yaml.Parse(src)
+	case "ndjson", "jsonl":
+		return nil, fmt.Errorf("%s input is only supported by encode", format)
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+}
+
+// inputFormat returns the -from format, or the input file extension when
+// -from is not set.
+func (c *command) inputFormat() string {
+	if c.from != "" {
+		return strings.ToLower(c.from)
+	}
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(c.flags.Arg(0))), ".")
+}
+
+// isNDJSON reports whether format names JSON Lines.
+func isNDJSON(format string) bool {
+	return format == "ndjson" || format == "jsonl"
+}
+
+// parseTOON decodes TOON input. A document holding only a top-level table or
+// list is returned as that list.
+func (c *command) parseTOON(src []byte) (any, error) {
+	data, err := gotoon.NewDecoder(c.config()).Decode(string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	if items, ok := data["items"].([]any); ok && len(data) == 1 {
+		return items, nil
+	}
+	return data, nil
+}
+
+// parseJSON decodes JSON keeping integers as int instead of float64.
+func parseJSON(src []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(src))
+	decoder.UseNumber()
+
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	return convertNumbers(data), nil
+}
+
+// convertNumbers replaces json.Number values with int or float64.
+func convertNumbers(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]any:
+		for k, item := range val {
+			val[k] = convertNumbers(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = convertNumbers(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,351 @@
+// Command gotoon converts data to and from TOON and inspects TOON documents.
+//
+// Usage:
+//
+//	gotoon <command> [flags] [file]
+//
+// Input is read from file when given, otherwise from stdin.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/b92c/gotoon"
+	"github.com/b92c/gotoon/yaml"
+)
+
+const usage = `Usage: gotoon <command> [flags] [file]
+
+Commands:
+  encode    convert JSON, NDJSON, CSV or YAML input to TOON
+  decode    convert TOON input to JSON, NDJSON, CSV or YAML
+  stats     compare JSON and TOON sizes for JSON input
+  validate  check TOON input in strict mode
+  fmt       reformat TOON input canonically
+  gen       generate Go struct types from sample JSON, CSV, YAML or TOON input
+
+Run "gotoon <command> -h" for command flags.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	commands := map[string]func(*command) error{
+		"encode":   runEncode,
+		"decode":   runDecode,
+		"stats":    runStats,
+		"validate": runValidate,
+		"fmt":      runFmt,
+		"gen":      runGen,
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+
+	handler, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "gotoon: unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+
+	cmd := newCommand(name, stdin, stdout, stderr)
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if err := handler(cmd); err != nil {
+		fmt.Fprintf(stderr, "gotoon %s: %v\n", name, err)
+		return 1
+	}
+
+	return 0
+}
+
+// command holds the flags and streams shared by all subcommands.
+type command struct {
+	name   string
+	flags  *flag.FlagSet
+	stdin  io.Reader
+	stdout io.Writer
+
+	minRows    int
+	maxDepth   int
+	omit       string
+	omitKeys   string
+	aliases    string
+	dateFormat string
+	truncate   int
+	precision  int
+	dictionary bool
+	hoist      bool
+	normalize  bool
+	sparse     bool
+	typed      bool
+
+	from      string
+	compact   bool
+	write     bool
+	keepOrder bool
+	typeName  string
+	pkg       string
+	tags      string
+	to        string
+	path      string
+}
+
+func newCommand(name string, stdin io.Reader, stdout, stderr io.Writer) *command {
+	defaults := gotoon.DefaultConfig()
+	cmd := &command{
+		name:   name,
+		flags:  flag.NewFlagSet("gotoon "+name, flag.ContinueOnError),
+		stdin:  stdin,
+		stdout: stdout,
+	}
+
+	fs := cmd.flags
+	fs.SetOutput(stderr)
+	fs.IntVar(&cmd.minRows, "min-rows", defaults.MinRowsForTable, "minimum number of items to use table format")
+	fs.IntVar(&cmd.maxDepth, "max-depth", defaults.MaxFlattenDepth, "how many levels of nested objects to flatten")
+	fs.StringVar(&cmd.omit, "omit", "", `comma-separated value types to omit: "null", "empty", "false" or "all"`)
+	fs.StringVar(&cmd.omitKeys, "omit-keys", "", "comma-separated keys to omit")
+	fs.StringVar(&cmd.aliases, "alias", "", `comma-separated key aliases, e.g. "description=desc"`)
+	fs.StringVar(&cmd.dateFormat, "date-format", defaults.DateFormat, "Go time layout for dates")
+	fs.IntVar(&cmd.truncate, "truncate", defaults.TruncateStrings, "maximum string length (0 disables)")
+	fs.IntVar(&cmd.precision, "precision", defaults.NumberPrecision, "maximum float decimal places (-1 disables)")
+	fs.BoolVar(&cmd.dictionary, "dictionary", defaults.DictionaryEncoding, "enable dictionary encoding for repeated values")
+	fs.BoolVar(&cmd.hoist, "hoist", defaults.HoistConstantColumns, "hoist constant columns into table attributes")
+	fs.BoolVar(&cmd.normalize, "normalize", defaults.NormalizeObjects, "extract repeated nested objects into reference tables")
+	fs.BoolVar(&cmd.sparse, "sparse", defaults.SparseTables, "encode objects with missing keys as sparse tables")
+	fs.BoolVar(&cmd.typed, "typed", defaults.TypedHeaders, "declare column types in table headers")
+
+	switch name {
+	case "encode":
+		fs.StringVar(&cmd.from, "from", "", `input format: "json", "ndjson", "csv", "yaml" or "toon" (default: from file extension, else json)`)
+	case "stats":
+		fs.StringVar(&cmd.from, "from", "", `input format: "json", "csv", "yaml" or "toon" (default: from file extension, else json)`)
+	case "gen":
+		fs.StringVar(&cmd.from, "from", "", `input format: "json", "csv", "yaml" or "toon" (default: from file extension, else json)`)
+		fs.StringVar(&cmd.typeName, "type", "Item", "name of the generated top-level type")
+		fs.StringVar(&cmd.pkg, "package", "main", "package name of the generated file")
+		fs.StringVar(&cmd.tags, "tags", "toon,json", "comma-separated struct tag keys")
+	case "decode":
+		fs.BoolVar(&cmd.compact, "compact", false, "write compact JSON")
+		fs.StringVar(&cmd.to, "to", "json", `output format: "json", "ndjson", "csv" or "yaml"`)
+		fs.StringVar(&cmd.path, "path", "", "dotted path of the table to write as CSV (default: the top-level table)")
+	case "fmt":
+		fs.BoolVar(&cmd.write, "w", false, "write result to the input file instead of stdout")
+		fs.BoolVar(&cmd.keepOrder, "keep-order", false, "keep keys and columns in source order")
+	}
+
+	return cmd
+}
+
+// config builds a gotoon.Config from the parsed flags.
+func (c *command) config() *gotoon.Config {
+	config := gotoon.DefaultConfig()
+	config.MinRowsForTable = c.minRows
+	config.MaxFlattenDepth = c.maxDepth
+	config.Omit = splitList(c.omit)
+	config.OmitKeys = splitList(c.omitKeys)
+	config.DateFormat = c.dateFormat
+	config.TruncateStrings = c.truncate
+	config.NumberPrecision = c.precision
+	config.DictionaryEncoding = c.dictionary
+	config.HoistConstantColumns = c.hoist
+	config.NormalizeObjects = c.normalize
+	config.SparseTables = c.sparse
+	config.TypedHeaders = c.typed
+
+	for _, alias := range splitList(c.aliases) {
+		if key, short, ok := strings.Cut(alias, "="); ok {
+			config.KeyAliases[key] = short
+		}
+	}
+
+	return config
+}
+
+// input reads the file named by the first argument, or stdin.
+func (c *command) input() ([]byte, error) {
+	if path := c.flags.Arg(0); path != "" {
+		return os.ReadFile(path)
+	}
+	return io.ReadAll(c.stdin)
+}
+
+// open opens the file named by the first argument, or returns stdin.
+func (c *command) open() (io.ReadCloser, error) {
+	if path := c.flags.Arg(0); path != "" {
+		return os.Open(path)
+	}
+	return io.NopCloser(c.stdin), nil
+}
+
+func runEncode(c *command) error {
+	if isNDJSON(c.inputFormat()) {
+		r, err := c.open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return gotoon.FromNDJSON(c.stdout, r, &gotoon.NDJSONOptions{Config: c.config()})
+	}
+
+	data, err := c.readData()
+	if err != nil {
+		return err
+	}
+
+	toon, err := gotoon.NewEncoder(c.config()).Encode(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, toon)
+	return err
+}
+
+func runDecode(c *command) error {
+	if isNDJSON(c.to) {
+		r, err := c.open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return gotoon.ToNDJSON(c.stdout, r, &gotoon.NDJSONOptions{Config: c.config()})
+	}
+
+	src, err := c.input()
+	if err != nil {
+		return err
+	}
+
+	switch c.to {
+	case "json":
+	case "csv":
+		return gotoon.ToCSV(c.stdout, string(src), &gotoon.CSVOptions{Config: c.config(), Path: c.path})
+	case "yaml":
+		out, err := yaml.FromTOON(string(src), c.config())
+		if err != nil {
+			return err
+		}
+		_, err = c.stdout.Write(out)
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q", c.to)
+	}
+
+	decoded, err := gotoon.NewDecoder(c.config()).Decode(string(src))
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if c.compact {
+		out, err = json.Marshal(decoded)
+	} else {
+		out, err = json.MarshalIndent(decoded, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, string(out))
+	return err
+}
+
+func runStats(c *command) error {
+	data, err := c.readData()
+	if err != nil {
+		return err
+	}
+
+	diff, err := gotoon.NewEncoder(c.config()).Diff(data)
+	if err != nil {
+		return err
+	}
+	diff["savings_percent"] = fmt.Sprintf("%.1f", diff["savings_percent"])
+
+	stats, err := gotoon.Encode(diff)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, stats)
+	return err
+}
+
+func runValidate(c *command) error {
+	src, err := c.input()
+	if err != nil {
+		return err
+	}
+
+	config := c.config()
+	config.Strict = true
+
+	if _, err := gotoon.NewDecoder(config).Decode(string(src)); err != nil {
+		var syntaxErr *gotoon.SyntaxError
+		if errors.As(err, &syntaxErr) && c.flags.Arg(0) != "" {
+			return fmt.Errorf("%s:%d:%d: %s", c.flags.Arg(0), syntaxErr.Line, syntaxErr.Column, syntaxErr.Msg)
+		}
+		return err
+	}
+
+	_, err = fmt.Fprintln(c.stdout, "ok")
+	return err
+}
+
+func runFmt(c *command) error {
+	src, err := c.input()
+	if err != nil {
+		return err
+	}
+
+	formatted, err := gotoon.Format(string(src), &gotoon.FormatOptions{PreserveOrder: c.keepOrder})
+	if err != nil {
+		return err
+	}
+
+	if c.write {
+		path := c.flags.Arg(0)
+		if path == "" {
+			return errors.New("-w requires a file argument")
+		}
+		return os.WriteFile(path, []byte(formatted+"\n"), 0o644)
+	}
+
+	_, err = fmt.Fprintln(c.stdout, formatted)
+	return err
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	list := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestEncodeCommand(t *testing.T) {
+	input := `{"users":[{"id":1,"name":"Alice","tenant":"acme"},{"id":2,"name":"Bob","tenant":"acme"}]}`
+
+	out, stderr, code := runCLI(t, input, "encode", "-hoist")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "users:\n  items[2]{id,name}@{tenant=acme}:\n    1,Alice\n    2,Bob\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+}
+
+func TestEncodeCommandFromCSV(t *testing.T) {
+	input := "id,customer.name,total\n1,Alice,9.5\n2,\"Bob, Jr\",12\n"
+
+	out, stderr, code := runCLI(t, input, "encode", "-from", "csv")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "items[2]{customer.name,id,total}:\n  Alice,1,9.5\n  Bob\\, Jr,2,12\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+}
+
+func TestEncodeCommandFromYAML(t *testing.T) {
+	input := "# users\n- id: 1\n  name: Alice\n- id: 2\n  name: Bob\n"
+
+	out, stderr, code := runCLI(t, input, "encode", "-from", "yaml")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "items[2]{id,name}:\n  1,Alice\n  2,Bob\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+}
+
+func TestEncodeCommandFromNDJSON(t *testing.T) {
+	input := "{\"id\":1,\"user\":{\"name\":\"Alice\"}}\n{\"id\":2,\"user\":{\"name\":\"Bob\"}}\n"
+
+	out, stderr, code := runCLI(t, input, "encode", "-from", "ndjson")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "items[2]{id,user.name}:\n  1,Alice\n  2,Bob\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+}
+
+func TestDecodeCommand(t *testing.T) {
+	out, stderr, code := runCLI(t, "items[2]{id,name}:\n  1,Alice\n  2,Bob\n", "decode", "-compact")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("Expected JSON output, got: %s", out)
+	}
+	if items := decoded["items"].([]any); len(items) != 2 {
+		t.Errorf("Expected 2 items, got: %s", out)
+	}
+}
+
+func TestDecodeCommandToCSV(t *testing.T) {
+	input := "report:\n  orders:\n    items[2]{customer.name,id}:\n      Bob\\, Jr,1\n      Alice,2\n"
+
+	out, stderr, code := runCLI(t, input, "decode", "-to", "csv", "-path", "report.orders")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "customer.name,id\n\"Bob, Jr\",1\nAlice,2\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+}
+
+func TestDecodeCommandToYAML(t *testing.T) {
+	input := "service:\n  name: billing\n  owners:\n    - ana\n    - bo\n"
+
+	out, stderr, code := runCLI(t, input, "decode", "-to", "yaml")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "service:\n  name: billing\n  owners:\n    - ana\n    - bo\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+}
+
+func TestDecodeCommandToNDJSON(t *testing.T) {
+	out, stderr, code := runCLI(t, "items[2]{id,user.name}:\n  1,Alice\n  2,Bob\n", "decode", "-to", "ndjson")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "{\"id\":1,\"user\":{\"name\":\"Alice\"}}\n{\"id\":2,\"user\":{\"name\":\"Bob\"}}\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	input := `[{"id":1,"name":"Alice"},{"id":2,"name":"Bob"}]`
+
+	out, stderr, code := runCLI(t, input, "stats")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	for _, key := range []string{"json_chars: 47", "toon_chars:", "savings_percent:", "toon_tokens_est:"} {
+		if !strings.Contains(out, key) {
+			t.Errorf("Expected %q in stats output, got: %s", key, out)
+		}
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.toon")
+	if err := os.WriteFile(path, []byte("items[2]{id,name}:\n  1,Alice\n  2,Bob,extra\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stderr, code := runCLI(t, "", "validate", path)
+	if code != 1 {
+		t.Fatalf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr, path+":3:3: row has 3 cells, expected 2") {
+		t.Errorf("Expected positioned error, got: %s", stderr)
+	}
+
+	out, _, code := runCLI(t, "name: Alice\n", "validate")
+	if code != 0 || out != "ok\n" {
+		t.Errorf("Expected valid input to pass, got code %d: %s", code, out)
+	}
+}
+
+func TestFmtCommand(t *testing.T) {
+	input := "name: Alice\nitems_count: 2\nlist:\n    - b\n    - a\n"
+
+	out, stderr, code := runCLI(t, input, "fmt")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	expected := "items_count: 2\nlist:\n  - b\n  - a\nname: Alice\n"
+	if out != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, out)
+	}
+
+	path := filepath.Join(t.TempDir(), "prompt.toon")
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, code := runCLI(t, "", "fmt", "-w", path); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+	if written, _ := os.ReadFile(path); string(written) != expected {
+		t.Errorf("Expected file to be rewritten, got: %s", written)
+	}
+}
+
+func TestGenCommand(t *testing.T) {
+	input := "items[2]{customer.name,id,placed_at,total}:\n  Alice,1,2024-01-02T10:00:00Z,9.5\n  Bob,2,2024-01-03T11:00:00Z,12\n"
+
+	out, stderr, code := runCLI(t, input, "gen", "-from", "toon", "-type", "Order", "-package", "models")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	for _, want := range []string{
+		"// Code generated by gotoon gen. DO NOT EDIT.",
+		"package models",
+		`import "time"`,
+		"type Order struct {",
+		"Customer OrderCustomer `toon:\"customer\" json:\"customer\"`",
+		"PlacedAt time.Time",
+		"Total    float64",
+		"type OrderCustomer struct {",
+		"Name string `toon:\"name\" json:\"name\"`",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestGenCommandFromJSON(t *testing.T) {
+	input := `[{"id":1,"email":"a@example.com"},{"id":2}]`
+
+	out, stderr, code := runCLI(t, input, "gen", "-tags", "toon")
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(out, "Email string `toon:\"email,omitempty\"`") || strings.Contains(out, "import") {
+		t.Errorf("Unexpected output:\n%s", out)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	_, stderr, code := runCLI(t, "", "explode")
+	if code != 2 || !strings.Contains(stderr, `unknown command "explode"`) {
+		t.Errorf("Expected usage error, got code %d: %s", code, stderr)
+	}
+}
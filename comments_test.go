@@ -0,0 +1,131 @@
+package gotoon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodeComments(t *testing.T) {
+	config := DefaultConfig()
+	config.Comments = map[string]string{
+		"user":        "account owner",
+		"user.email":  "verified",
+		"orders":      "prices in cents\nnewest first",
+		"tags.1":      "legacy",
+		"missing.key": "never written",
+	}
+
+	data := map[string]any{
+		"user": map[string]any{"email": "a@example.com", "name": "Alice"},
+		"orders": []any{
+			map[string]any{"id": 1, "total": 950},
+			map[string]any{"id": 2, "total": 1200},
+		},
+		"tags": []any{"new", "old"},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := `# prices in cents
+# newest first
+orders:
+  items[2]{id,total}:
+    1,950
+    2,1200
+tags:
+  - new
+  # legacy
+  - old
+# account owner
+user:
+  # verified
+  email: a@example.com
+  name: Alice`
+
+	if toon != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded["tags"], []any{"new", "old"}) {
+		t.Errorf("Expected comments to be skipped, got tags: %+v", decoded["tags"])
+	}
+	if user := decoded["user"].(map[string]any); len(user) != 2 {
+		t.Errorf("Expected comments to be skipped, got user: %+v", user)
+	}
+}
+
+func TestDecodeWithCommentsRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.Comments = map[string]string{
+		"count":          "total rows",
+		"orders.items":   "one row per order",
+		"groups.0":       "first group",
+		"groups.1.name":  "display name",
+		"settings.theme": "dark or light",
+	}
+
+	data := map[string]any{
+		"count": 2,
+		"orders": []any{
+			map[string]any{"id": 1},
+			map[string]any{"id": 2},
+		},
+		"groups":   []any{"admins", map[string]any{"id": 7, "name": "staff"}},
+		"settings": map[string]any{"theme": "dark"},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, comments, err := NewDecoder(nil).DecodeWithComments(toon)
+	if err != nil {
+		t.Fatalf("DecodeWithComments failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(comments, config.Comments) {
+		t.Errorf("Comments did not round-trip.\nExpected: %v\nGot:      %v\n%s", config.Comments, comments, toon)
+	}
+
+	plain, _ := Decode(toon)
+	if !reflect.DeepEqual(decoded, plain) {
+		t.Errorf("Expected same data as Decode.\nExpected: %+v\nGot:      %+v", plain, decoded)
+	}
+}
+
+func TestHashValuesAreNotComments(t *testing.T) {
+	data := map[string]any{
+		"channel": "#general",
+		"tags":    []any{"#go", "#toon"},
+	}
+
+	toon, _ := Encode(data)
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if decoded["channel"] != "#general" || !reflect.DeepEqual(decoded["tags"], []any{"#go", "#toon"}) {
+		t.Errorf("Expected values starting with # to survive, got: %+v", decoded)
+	}
+}
+
+func TestFormatKeepsComments(t *testing.T) {
+	formatted, err := Format("# about b\nb: 1\n# about a\na: 2\n# trailing", nil)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := "# about a\na: 2\n# about b\nb: 1\n# trailing"
+	if formatted != expected {
+		t.Errorf("Expected comments to move with their keys.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
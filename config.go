@@ -1,5 +1,7 @@
 package gotoon
 
+import "reflect"
+
 // Config holds configuration options for TOON encoding and decoding.
 type Config struct {
 	// MinRowsForTable is the minimum number of items required to use table format.
@@ -7,7 +9,8 @@ type Config struct {
 	MinRowsForTable int
 
 	// MaxFlattenDepth controls how many levels deep to flatten nested objects.
-	// Objects nested deeper than this will be JSON-encoded as strings.
+	// Objects nested deeper than this are JSON-encoded in a column marked
+	// ":json" in the table header, and restored to structures on decode.
 	MaxFlattenDepth int
 
 	// EscapeStyle determines how to escape special characters in string values.
@@ -36,20 +39,93 @@ type Config struct {
 	// NumberPrecision specifies the maximum decimal places for float values.
 	// When -1, floats are passed through as-is.
 	NumberPrecision int
+
+	// TypeTransformers rewrite values of a Go type before they are encoded,
+	// keyed by the type, e.g. reflect.TypeFor[time.Time](). They receive the
+	// value as given, before structs and marshalers are converted.
+	TypeTransformers map[reflect.Type]Transformer
+
+	// PathTransformers rewrite values before they are encoded, keyed by a
+	// dotted path pattern. Paths name list items "*" as in TypeHints. In a
+	// pattern, "*" matches exactly one key or list item and "**" any number
+	// of them, so "orders.*.total" matches every order's total,
+	// "*.description" only descriptions one level down, and
+	// "**.description" descriptions at any depth, such as
+	// "orders.*.description". Patterns match whole paths. An exact match
+	// wins over wildcards; among wildcard patterns the one with the fewest
+	// "**", then the fewest "*", then the first in sort order is used. They
+	// run after TypeTransformers, and DateFormat, TruncateStrings and
+	// NumberPrecision still apply to the values they return.
+	PathTransformers map[string]Transformer
+
+	// DictionaryEncoding enables per-table value dictionaries for low-cardinality
+	// string columns. Each distinct value is written once in a "&column:" line and
+	// rows reference it by index. Columns are only encoded when it saves characters.
+	DictionaryEncoding bool
+
+	// HoistConstantColumns moves columns that hold the same value in every row
+	// out of the table and into header attributes, e.g. "items[3]{id}@{currency=EUR}:".
+	HoistConstantColumns bool
+
+	// NormalizeObjects extracts nested objects that repeat across table rows
+	// (e.g. the same customer on many orders) into a reference table written
+	// once inside the parent table. Rows keep a "*key" column holding the index
+	// of their object in the reference table.
+	NormalizeObjects bool
+
+	// SparseTables encodes arrays of objects with differing keys as a single
+	// table instead of a list. Columns missing from some items are marked with
+	// "?" in the header; an empty cell in such a column means the key is absent
+	// and an explicit null is written as "null".
+	SparseTables bool
+
+	// TypedHeaders declares the type of table columns whose values share one,
+	// e.g. "items[3]{id:int,price:float,sku:str,at:time}:". The decoder then
+	// converts every cell of the column to that type instead of guessing from
+	// its text, so a float column holding "10" still decodes as float64.
+	TypedHeaders bool
+
+	// TypeHints gives the decoder the type of values whose text is ambiguous,
+	// keyed by dotted path with "*" for any list item, e.g. "user.zip": "str"
+	// or "orders.*.total": "float". Paths are matched as in PathTransformers,
+	// so "*" also stands for any map key, e.g. "labels.*": "str". Types are
+	// those of typed headers; a type declared in a table header takes
+	// precedence. Values that don't match their hint are decoded as usual.
+	TypeHints map[string]string
+
+	// Strict makes the decoder reject malformed input with a *SyntaxError that
+	// reports the line and column, instead of skipping what it can't parse.
+	Strict bool
+
+	// Comments attaches "# " comment lines to the output, keyed by the dotted
+	// path of the key they annotate, e.g. "orders" or "user.email". List items
+	// are addressed by index ("tags.0") and a top-level table by "items".
+	// Multi-line comments are written as one comment line per line.
+	Comments map[string]string
 }
 
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
-		MinRowsForTable: 2,
-		MaxFlattenDepth: 3,
-		EscapeStyle:     "backslash",
-		Omit:            []string{},
-		OmitKeys:        []string{},
-		KeyAliases:      make(map[string]string),
-		DateFormat:      "",
-		TruncateStrings: 0,
-		NumberPrecision: -1,
+		MinRowsForTable:      2,
+		MaxFlattenDepth:      3,
+		EscapeStyle:          "backslash",
+		Omit:                 []string{},
+		OmitKeys:             []string{},
+		KeyAliases:           make(map[string]string),
+		DateFormat:           "",
+		TruncateStrings:      0,
+		NumberPrecision:      -1,
+		TypeTransformers:     make(map[reflect.Type]Transformer),
+		PathTransformers:     make(map[string]Transformer),
+		DictionaryEncoding:   false,
+		HoistConstantColumns: false,
+		NormalizeObjects:     false,
+		SparseTables:         false,
+		TypedHeaders:         false,
+		TypeHints:            make(map[string]string),
+		Strict:               false,
+		Comments:             make(map[string]string),
 	}
 }
 
@@ -0,0 +1,227 @@
+package gotoon
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVOptions configures FromCSV, ReadCSV and ToCSV. A nil *CSVOptions uses the
+// defaults.
+type CSVOptions struct {
+	// Comma is the field delimiter. Zero means ','.
+	Comma rune
+
+	// Config configures the encoder of FromCSV and the decoder and flattener
+	// of ToCSV. Nil means DefaultConfig.
+	Config *Config
+
+	// RawStrings turns off type detection, so every non-empty cell is read as
+	// a string.
+	RawStrings bool
+
+	// Path selects the table ToCSV exports as a dotted key path, e.g.
+	// "report.orders". Empty means the top-level table, or the only list at
+	// the top level of the document.
+	Path string
+}
+
+// config returns the configured Config or the default.
+func (o *CSVOptions) config() *Config {
+	if o == nil || o.Config == nil {
+		return DefaultConfig()
+	}
+	return o.Config
+}
+
+// ReadCSV reads a CSV document with a header row into a list of objects.
+// Dot-notation headers such as "customer.name" become nested objects. Unless
+// RawStrings is set, cells are typed: empty cells become nil, "true" and
+// "false" booleans, and numbers int or float64. Numbers with leading zeros,
+// such as zip codes, stay strings.
+func ReadCSV(r io.Reader, opts *CSVOptions) ([]any, error) {
+	reader := csv.NewReader(r)
+	if opts != nil && opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return []any{}, nil
+	}
+
+	raw := opts != nil && opts.RawStrings
+	columns := records[0]
+	rows := make([][]any, len(records)-1)
+	for i, record := range records[1:] {
+		rows[i] = make([]any, len(record))
+		for j, cell := range record {
+			if raw {
+				if cell != "" {
+					rows[i][j] = cell
+				}
+				continue
+			}
+			rows[i][j] = parseCSVValue(cell)
+		}
+	}
+
+	objects := NewArrayUnflattener().Unflatten(rows, columns)
+	items := make([]any, len(objects))
+	for i, obj := range objects {
+		items[i] = obj
+	}
+	return items, nil
+}
+
+// FromCSV converts a CSV document with a header row to TOON. See ReadCSV for
+// how cells are typed.
+func FromCSV(r io.Reader, opts *CSVOptions) (string, error) {
+	items, err := ReadCSV(r, opts)
+	if err != nil {
+		return "", err
+	}
+	return NewEncoder(opts.config()).Encode(items)
+}
+
+// ToCSV writes a table of a TOON document as CSV with a header row. Nested
+// objects become dot-notation columns, arrays are written as JSON, times use
+// the configured DateFormat or RFC 3339, and nil or missing values are left
+// empty. Cells are quoted as needed by encoding/csv.
+func ToCSV(w io.Writer, toon string, opts *CSVOptions) error {
+	config := opts.config()
+	decoded, err := NewDecoder(config).Decode(toon)
+	if err != nil {
+		return err
+	}
+
+	path := ""
+	if opts != nil {
+		path = opts.Path
+	}
+	items, err := csvTable(decoded, path)
+	if err != nil {
+		return err
+	}
+
+	flattened := NewArrayFlattener(config.MaxFlattenDepth).Flatten(items)
+
+	writer := csv.NewWriter(w)
+	if opts != nil && opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+
+	if err := writer.Write(flattened.Columns); err != nil {
+		return err
+	}
+	for _, row := range flattened.Rows {
+		record := make([]string, len(row))
+		for j, value := range row {
+			record[j] = formatCSVValue(value, config.DateFormat)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvTable finds the list of objects to export.
+func csvTable(data map[string]any, path string) ([]any, error) {
+	var value any
+	switch {
+	case path != "":
+		var current any = data
+		for _, segment := range strings.Split(path, ".") {
+			m, ok := current.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("no table at %q", path)
+			}
+			current = m[segment]
+		}
+		value = current
+	case data["items"] != nil:
+		value = data["items"]
+	default:
+		var lists []string
+		for _, key := range sortedKeys(data) {
+			if _, ok := data[key].([]any); ok {
+				lists = append(lists, key)
+			}
+		}
+		if len(lists) != 1 {
+			return nil, fmt.Errorf("document has %d top-level lists %v; set CSVOptions.Path", len(lists), lists)
+		}
+		path, value = lists[0], data[lists[0]]
+	}
+
+	items, ok := value.([]any)
+	if !ok || !isArrayOfObjects(items) {
+		if path == "" {
+			path = "items"
+		}
+		return nil, fmt.Errorf("%q is not a table", path)
+	}
+	return items, nil
+}
+
+// parseCSVValue detects the type of a CSV cell.
+func parseCSVValue(cell string) any {
+	switch cell {
+	case "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	digits := strings.TrimPrefix(cell, "-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return cell
+	}
+
+	if i, err := strconv.Atoi(cell); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil && strings.Contains(cell, ".") {
+		return f
+	}
+
+	return cell
+}
+
+// formatCSVValue writes a decoded value as a CSV cell.
+func formatCSVValue(value any, dateFormat string) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case time.Time:
+		if dateFormat != "" {
+			return val.Format(dateFormat)
+		}
+		return val.Format(time.RFC3339)
+	case []any, map[string]any:
+		if data, err := json.Marshal(val); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
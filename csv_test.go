@@ -0,0 +1,108 @@
+package gotoon
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromCSV(t *testing.T) {
+	input := "id,customer.name,customer.zip,total,paid,note\n" +
+		"1,\"Bob, Jr\",01234,9.5,true,\n" +
+		"2,Alice,90210,12,false,\"said \"\"hi\"\"\"\n"
+
+	toon, err := FromCSV(strings.NewReader(input), nil)
+	if err != nil {
+		t.Fatalf("FromCSV failed: %v", err)
+	}
+
+	expected := "items[2]{customer.name,customer.zip,id,note,paid,total}:\n" +
+		"  Bob\\, Jr,01234,1,,true,9.5\n" +
+		"  Alice,90210,2,said \"hi\",false,12"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+}
+
+func TestReadCSVTypes(t *testing.T) {
+	items, err := ReadCSV(strings.NewReader("a;b;c;d;e\n007;-3;0.25;x;\n"), &CSVOptions{Comma: ';'})
+	if err != nil {
+		t.Fatalf("ReadCSV failed: %v", err)
+	}
+
+	expected := []any{map[string]any{"a": "007", "b": -3, "c": 0.25, "d": "x", "e": nil}}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Unexpected items.\nExpected: %+v\nGot:      %+v", expected, items)
+	}
+
+	items, _ = ReadCSV(strings.NewReader("a,b\n1,true\n"), &CSVOptions{RawStrings: true})
+	if !reflect.DeepEqual(items, []any{map[string]any{"a": "1", "b": "true"}}) {
+		t.Errorf("Expected raw strings, got %+v", items)
+	}
+}
+
+func TestToCSV(t *testing.T) {
+	toon := "items[2]{at,customer.name,id,tags:json}:\n" +
+		"  2024-01-02T10:00:00Z,Bob\\, Jr,1,[\"a\"\\,\"b\"]\n" +
+		"  ,Alice,2,"
+
+	var out bytes.Buffer
+	if err := ToCSV(&out, toon, nil); err != nil {
+		t.Fatalf("ToCSV failed: %v", err)
+	}
+
+	expected := "at,customer.name,id,tags\n" +
+		"2024-01-02T10:00:00Z,\"Bob, Jr\",1,\"[\"\"a\"\",\"\"b\"\"]\"\n" +
+		",Alice,2,\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected CSV.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.TypedHeaders = true
+
+	data := []any{
+		map[string]any{"id": 1, "user": map[string]any{"name": "Bob, Jr", "zip": "01234"}, "score": 9.5},
+		map[string]any{"id": 2, "user": map[string]any{"name": "Ann \"A\"", "zip": "90210"}, "score": 3.0},
+	}
+	toon, _ := NewEncoder(config).Encode(data)
+
+	var out bytes.Buffer
+	if err := ToCSV(&out, toon, &CSVOptions{Config: config}); err != nil {
+		t.Fatalf("ToCSV failed: %v", err)
+	}
+	items, err := ReadCSV(&out, nil)
+	if err != nil {
+		t.Fatalf("ReadCSV failed: %v", err)
+	}
+
+	// CSV has no types: whole numbers are read back as int, except those with
+	// leading zeros.
+	data[1].(map[string]any)["score"] = 3
+	data[1].(map[string]any)["user"].(map[string]any)["zip"] = 90210
+	if !reflect.DeepEqual(items, data) {
+		t.Errorf("Round trip mismatch.\nExpected: %+v\nGot:      %+v", data, items)
+	}
+}
+
+func TestToCSVSelectsTable(t *testing.T) {
+	toon := "meta:\n  at: 2024-01-02\nusers:\n  items[1]{id}:\n    1"
+
+	var out bytes.Buffer
+	config := DefaultConfig()
+	config.DateFormat = time.DateOnly
+	if err := ToCSV(&out, toon, &CSVOptions{Config: config}); err != nil || out.String() != "id\n1\n" {
+		t.Errorf("Expected the only list to be exported, got %q, %v", out.String(), err)
+	}
+
+	if err := ToCSV(&out, "users:\n  - a\n  - b", nil); err == nil || !strings.Contains(err.Error(), "not a table") {
+		t.Errorf("Expected a list of scalars to be rejected, got %v", err)
+	}
+	if err := ToCSV(&out, "a:\n  items[1]{id}:\n    1\nb:\n  items[1]{id}:\n    2", nil); err == nil || !strings.Contains(err.Error(), "CSVOptions.Path") {
+		t.Errorf("Expected an ambiguous document to be rejected, got %v", err)
+	}
+}
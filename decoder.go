@@ -1,9 +1,11 @@
 package gotoon
 
 import (
-	"regexp"
+	"fmt"
 	"strconv"
 	"strings"
+
+	"github.com/b92c/gotoon/ast"
 )
 
 // Decoder handles decoding TOON format strings to Go data structures.
@@ -23,105 +25,327 @@ func NewDecoder(config *Config) *Decoder {
 	}
 }
 
+// SyntaxError describes malformed TOON input. The Decoder only returns it in
+// strict mode; Format always does.
+type SyntaxError = ast.SyntaxError
+
 // Decode converts a TOON format string to Go data structures.
+// Top-level tables and lists are returned under the "items" key.
 func (d *Decoder) Decode(toon string) (map[string]any, error) {
-	lines := strings.Split(toon, "\n")
-	result := make(map[string]any)
+	doc, err := ast.Parse(toon)
+	if err != nil && d.config.Strict {
+		return nil, err
+	}
 
-	type stackItem struct {
-		data   map[string]any
-		indent int
-		key    string
+	return d.decodeDocument(doc)
+}
+
+// DecodeWithComments decodes toon like Decode and also returns its "#" comments,
+// keyed by the dotted path of the entry that follows them in the same form as
+// Config.Comments. Comments not followed by an entry are dropped.
+func (d *Decoder) DecodeWithComments(toon string) (map[string]any, map[string]string, error) {
+	doc, err := ast.Parse(toon)
+	if err != nil && d.config.Strict {
+		return nil, nil, err
 	}
 
-	stack := []stackItem{{data: result, indent: -1, key: ""}}
+	data, err := d.decodeDocument(doc)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	i := 0
-	for i < len(lines) {
-		line := lines[i]
+	comments := make(map[string]string)
+	collectComments(doc.Nodes, "", comments)
+	return data, comments, nil
+}
 
-		if strings.TrimSpace(line) == "" {
-			i++
-			continue
-		}
+// decodeDocument evaluates a parsed document.
+func (d *Decoder) decodeDocument(doc *ast.Document) (map[string]any, error) {
+	state := &decodeState{Decoder: d}
+	value := state.evalBlock(doc.Nodes, "")
+	if state.err != nil {
+		return nil, state.err
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return v, nil
+	case []any:
+		return map[string]any{"items": v}, nil
+	default:
+		return map[string]any{}, nil
+	}
+}
 
-		indent := len(line) - len(strings.TrimLeft(line, " "))
-		content := strings.TrimSpace(line)
+// decodeState evaluates the syntax tree of a single Decode call.
+type decodeState struct {
+	*Decoder
+	err *SyntaxError
+}
 
-		for len(stack) > 1 && indent <= stack[len(stack)-1].indent {
-			stack = stack[:len(stack)-1]
-		}
+// failAt records an error at the given position when strict mode is enabled.
+// Only the first error is kept.
+func (s *decodeState) failAt(at ast.Pos, format string, args ...any) {
+	if !s.config.Strict || s.err != nil {
+		return
+	}
+	s.err = &SyntaxError{Line: at.Line, Column: at.Column, Msg: fmt.Sprintf(format, args...)}
+}
 
-		current := stack[len(stack)-1].data
+// evalBlock converts a block of nodes to a list, a scalar or an object. An
+// object consisting of a single table evaluates to the table's items.
+// Comments are skipped. path is the dotted path of the block's value, with
+// list items written as "*", used to look up type hints.
+func (s *decodeState) evalBlock(nodes []ast.Node, path string) any {
+	nodes = withoutComments(nodes)
+	if len(nodes) == 0 {
+		return nil
+	}
 
-		if match := regexp.MustCompile(`^items\[(\d+)\]\{([^\}]*)\}:$`).FindStringSubmatch(content); match != nil {
-			rowCount, _ := strconv.Atoi(match[1])
-			columnsStr := match[2]
+	// Top-level lists and tables are returned under the "items" key.
+	itemsPath := joinPath(path, "items")
+	if len(nodes) == 1 && path != "" {
+		itemsPath = path
+	}
 
-			var columns []string
-			if columnsStr != "" {
-				columns = strings.Split(columnsStr, ",")
-				for j := range columns {
-					columns[j] = strings.TrimSpace(columns[j])
-				}
+	switch first := nodes[0].(type) {
+	case *ast.ListItem:
+		if path == "" {
+			path = "items"
+		}
+		items := make([]any, 0, len(nodes))
+		for _, n := range nodes {
+			if item, ok := n.(*ast.ListItem); ok {
+				items = append(items, s.evalBlock(item.Body, joinPath(path, "*")))
 			}
+		}
+		return items
+	case *ast.Scalar:
+		return s.parseHinted(first.Value, path)
+	}
 
-			rows := [][]any{}
-			for j := 0; j < rowCount && (i+1+j) < len(lines); j++ {
-				rowLine := lines[i+1+j]
-				rowContent := strings.TrimSpace(rowLine)
+	result := make(map[string]any)
+	var table []any
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.KeyValue:
+			result[n.Key] = s.parseHinted(n.Value, joinPath(path, n.Key))
+		case *ast.Object:
+			if withoutComments(n.Body) == nil {
+				result[n.Key] = make(map[string]any)
+			} else {
+				result[n.Key] = s.evalBlock(n.Body, joinPath(path, n.Key))
+			}
+		case *ast.Table:
+			table = s.evalTable(n, joinPath(itemsPath, "*"))
+			result["items"] = table
+		}
+	}
+
+	if table != nil && len(result) == 1 {
+		return table
+	}
+
+	return result
+}
 
-				if rowContent == "" {
-					continue
-				}
+// evalTable converts a table node to its items, expanding dictionaries,
+// header attributes and reference tables. itemPath is the path of each item,
+// used to look up type hints for columns without a declared type.
+func (s *decodeState) evalTable(table *ast.Table, itemPath string) []any {
+	dicts := make(map[string][]any)
+	for _, dict := range table.Dictionaries {
+		dicts[dict.Column] = s.parseCells(dict.Values, 0)
+	}
+
+	columns := make([]string, len(table.Columns))
+	types := make([]string, len(table.Columns))
+	var optional, jsonColumns, typedColumns []int
+	for j, col := range table.Columns {
+		columns[j] = col.Name
+		types[j] = col.Type
+		if types[j] == "" {
+			types[j] = s.typeHint(joinPath(itemPath, col.Name))
+		}
+		if col.Optional {
+			optional = append(optional, j)
+		}
+		switch {
+		case col.Type == jsonColumnType:
+			jsonColumns = append(jsonColumns, j)
+		case !isScalarColumnType(types[j]):
+		case dicts[col.Name] != nil:
+			s.typeDictionary(table, col, types[j], dicts)
+		default:
+			typedColumns = append(typedColumns, j)
+		}
+	}
 
-				cells := d.parseRow(rowContent, len(columns))
-				rows = append(rows, cells)
+	rows := make([][]any, len(table.Rows))
+	absent := make([][]bool, len(table.Rows))
+	for i, row := range table.Rows {
+		cells := s.parseCells(row.Cells, len(columns))
+		rows[i] = cells
+
+		for _, col := range jsonColumns {
+			if col >= len(row.Cells) {
+				continue
+			}
+			value, err := decodeJSONCell(row.Cells[col].Raw)
+			if err != nil {
+				s.failAt(row.Pos, "invalid JSON in column %q: %v", columns[col], err)
 			}
+			cells[col] = value
+		}
 
-			i += rowCount
+		for _, col := range typedColumns {
+			if col >= len(row.Cells) {
+				continue
+			}
+			value, err := s.parseTyped(row.Cells[col].Raw, types[col])
+			if err != nil && table.Columns[col].Type != "" {
+				s.failAt(row.Cells[col].Pos, "%v in column %q", err, columns[col])
+			}
+			cells[col] = value
+		}
 
-			var items []any
-			if hasNestedColumns(columns) {
-				objects := d.unflattener.Unflatten(rows, columns)
-				items = make([]any, len(objects))
-				for idx, obj := range objects {
-					items[idx] = obj
-				}
-			} else {
-				items = d.rowsToObjects(rows, columns)
+		if len(optional) > 0 {
+			absent[i] = make([]bool, len(columns))
+			for _, col := range optional {
+				absent[i][col] = col >= len(row.Cells) || strings.TrimSpace(row.Cells[col].Raw) == ""
 			}
+		}
+	}
 
-			if len(stack) > 1 {
-				parentKey := stack[len(stack)-1].key
-				parentData := stack[len(stack)-2].data
-				parentData[parentKey] = items
-			} else {
-				current["_items"] = items
+	expandDictionaries(rows, columns, dicts)
+
+	if len(table.Attributes) > 0 {
+		keys, values := s.parseAttributes(table.Attributes, itemPath)
+		width := len(columns)
+		columns = append(columns, keys...)
+		for r := range rows {
+			rows[r] = append(rows[r][:width:width], values...)
+		}
+	}
+
+	var items []any
+	if len(optional) > 0 {
+		items = s.sparseRowsToObjects(rows, columns, absent)
+	} else if hasNestedColumns(columns) {
+		objects := s.unflattener.Unflatten(rows, columns)
+		items = make([]any, len(objects))
+		for idx, obj := range objects {
+			items[idx] = obj
+		}
+	} else {
+		items = s.rowsToObjects(rows, columns)
+	}
+
+	for _, ref := range table.References {
+		key := strings.TrimPrefix(ref.Name, referencePrefix)
+		s.unflattener.Join(items, key, s.evalTable(ref, joinPath(itemPath, key)))
+	}
+
+	return items
+}
+
+// collectComments records the comments in nodes under the path of the entry
+// they precede.
+func collectComments(nodes []ast.Node, path string, comments map[string]string) {
+	var pending []string
+	index := 0
+
+	attach := func(key string) {
+		if len(pending) > 0 {
+			comments[joinPath(path, key)] = strings.Join(pending, "\n")
+			pending = nil
+		}
+	}
+
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.Comment:
+			pending = append(pending, n.Text)
+		case *ast.KeyValue:
+			attach(n.Key)
+		case *ast.Object:
+			attach(n.Key)
+			collectComments(n.Body, joinPath(path, n.Key), comments)
+		case *ast.Table:
+			attach("items")
+		case *ast.ListItem:
+			key := strconv.Itoa(index)
+			attach(key)
+			collectComments(n.Body, joinPath(path, key), comments)
+			index++
+		default:
+			pending = nil
+		}
+	}
+}
+
+// typeHint returns the type hint configured for path, if any.
+func (d *Decoder) typeHint(path string) string {
+	hint, _ := lookupPath(d.config.TypeHints, path)
+	return hint
+}
+
+// parseHinted parses a "key: value" value or bare scalar, converting it to the
+// type hinted for path when it matches that type.
+func (d *Decoder) parseHinted(raw, path string) any {
+	if typ := d.typeHint(path); isScalarColumnType(typ) {
+		if value, err := d.parseTyped(raw, typ); err == nil {
+			return value
+		}
+	}
+	return d.parseValue(raw)
+}
+
+// typeDictionary converts the values of a typed column's dictionary to typ.
+// Mismatches are only reported when the header declares the type.
+func (s *decodeState) typeDictionary(table *ast.Table, col ast.Column, typ string, dicts map[string][]any) {
+	for _, dict := range table.Dictionaries {
+		if dict.Column != col.Name {
+			continue
+		}
+		for i, cell := range dict.Values {
+			value, err := s.parseTyped(cell.Raw, typ)
+			if err != nil && col.Type != "" {
+				s.failAt(cell.Pos, "%v in dictionary %q", err, col.Name)
 			}
-		} else if strings.HasSuffix(content, ":") && !strings.Contains(content, ": ") {
-			key := strings.TrimSuffix(content, ":")
-			current[key] = make(map[string]any)
-			stack = append(stack, stackItem{
-				data:   current[key].(map[string]any),
-				indent: indent,
-				key:    key,
-			})
-		} else if strings.Contains(content, ": ") {
-			parts := strings.SplitN(content, ": ", 2)
-			key := parts[0]
-			value := d.parseValue(parts[1])
-			current[key] = value
+			dicts[col.Name][i] = value
 		}
+	}
+}
+
+// withoutComments returns nodes with comments removed, or nil if nothing remains.
+func withoutComments(nodes []ast.Node) []ast.Node {
+	var kept []ast.Node
+	for _, n := range nodes {
+		if _, ok := n.(*ast.Comment); !ok {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
 
-		i++
+// parseCells parses the cells of a row, padding with nil up to expectedCount.
+func (d *Decoder) parseCells(cells []*ast.Cell, expectedCount int) []any {
+	values := make([]any, 0, len(cells))
+	for _, cell := range cells {
+		values = append(values, d.parseCell(cell.Raw))
 	}
 
-	if items, exists := result["_items"]; exists {
-		return map[string]any{"items": items}, nil
+	for len(values) < expectedCount {
+		values = append(values, nil)
 	}
 
-	return result, nil
+	return values
+}
+
+// parseCell parses a single raw cell.
+func (d *Decoder) parseCell(raw string) any {
+	return d.parseRow(raw, 1)[0]
 }
 
 // parseRow parses a CSV-like row with escape handling.
@@ -188,6 +412,11 @@ func (d *Decoder) parseValue(value string) any {
 		}
 	}
 
+	// A leading "-" is escaped in list items so it doesn't start a nested item.
+	if strings.HasPrefix(value, "\\-") {
+		value = value[1:]
+	}
+
 	value = strings.ReplaceAll(value, "\\n", "\n")
 	value = strings.ReplaceAll(value, "\\,", ",")
 	value = strings.ReplaceAll(value, "\\:", ":")
@@ -215,6 +444,30 @@ func (d *Decoder) rowsToObjects(rows [][]any, columns []string) []any {
 	return objects
 }
 
+// sparseRowsToObjects converts rows to objects, leaving out absent cells.
+func (d *Decoder) sparseRowsToObjects(rows [][]any, columns []string, absent [][]bool) []any {
+	objects := make([]any, len(rows))
+
+	for i, row := range rows {
+		var presentColumns []string
+		var presentCells []any
+		for j, col := range columns {
+			if j < len(absent[i]) && absent[i][j] {
+				continue
+			}
+			presentColumns = append(presentColumns, col)
+			if j < len(row) {
+				presentCells = append(presentCells, row[j])
+			} else {
+				presentCells = append(presentCells, nil)
+			}
+		}
+		objects[i] = d.unflattener.unflattenRow(presentCells, presentColumns)
+	}
+
+	return objects
+}
+
 // hasNestedColumns checks if any column contains a dot (nested path).
 func hasNestedColumns(columns []string) bool {
 	for _, col := range columns {
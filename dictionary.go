@@ -0,0 +1,92 @@
+package gotoon
+
+import "strconv"
+
+// dictionary holds the distinct escaped values of a low-cardinality column.
+type dictionary struct {
+	values []string
+	index  map[string]int
+}
+
+// buildDictionaries detects string columns whose values repeat often enough that
+// writing them once per table and referencing them by index saves characters.
+// The returned slice is indexed by column; columns not worth encoding are nil.
+func (e *Encoder) buildDictionaries(rows [][]any, cells [][]string) []*dictionary {
+	if len(rows) < 2 {
+		return nil
+	}
+
+	dicts := make([]*dictionary, len(rows[0]))
+	for col := range dicts {
+		dict := &dictionary{index: make(map[string]int)}
+		plainCost, refCost, eligible := 0, 0, true
+
+		for i, row := range rows {
+			if row[col] == nil {
+				continue
+			}
+			if _, ok := row[col].(string); !ok {
+				eligible = false
+				break
+			}
+
+			value := cells[i][col]
+			if value == "" {
+				continue
+			}
+
+			idx, exists := dict.index[value]
+			if !exists {
+				idx = len(dict.values)
+				dict.index[value] = idx
+				dict.values = append(dict.values, value)
+			}
+
+			plainCost += len(value)
+			refCost += len(strconv.Itoa(idx))
+		}
+
+		if !eligible || len(dict.values) == 0 {
+			continue
+		}
+
+		dictCost := len(dict.values) + 4
+		for _, v := range dict.values {
+			dictCost += len(v)
+		}
+
+		if refCost+dictCost < plainCost {
+			dicts[col] = dict
+		}
+	}
+
+	return dicts
+}
+
+// apply replaces the cells of the given column with dictionary references.
+func (d *dictionary) apply(cells [][]string, col int) {
+	for _, row := range cells {
+		if idx, ok := d.index[row[col]]; ok {
+			row[col] = strconv.Itoa(idx)
+		}
+	}
+}
+
+// expandDictionaries replaces dictionary references in rows with their values.
+func expandDictionaries(rows [][]any, columns []string, dicts map[string][]any) {
+	for col, name := range columns {
+		values, ok := dicts[name]
+		if !ok {
+			continue
+		}
+
+		for _, row := range rows {
+			if col >= len(row) {
+				continue
+			}
+			if idx, ok := row[col].(int); ok && idx >= 0 && idx < len(values) {
+				row[col] = values[idx]
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package gotoon
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDictionaryEncodingRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.DictionaryEncoding = true
+
+	statuses := []string{"confirmed", "pending", "cancelled"}
+	orders := make([]any, 12)
+	for i := range orders {
+		orders[i] = map[string]any{
+			"id":       i + 1,
+			"status":   statuses[i%len(statuses)],
+			"currency": "EUR",
+		}
+	}
+
+	toon, err := NewEncoder(config).Encode(map[string]any{"orders": orders})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.Contains(toon, "&status: confirmed,pending,cancelled") {
+		t.Errorf("Expected status dictionary line, got: %s", toon)
+	}
+	if !strings.Contains(toon, "&currency: EUR") {
+		t.Errorf("Expected currency dictionary line, got: %s", toon)
+	}
+	if strings.Count(toon, "confirmed") != 1 {
+		t.Errorf("Expected 'confirmed' to be written once, got: %s", toon)
+	}
+
+	decoded, err := NewDecoder(config).Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items, ok := decoded["orders"].([]any)
+	if !ok || len(items) != 12 {
+		t.Fatalf("Expected 12 orders, got: %+v", decoded["orders"])
+	}
+
+	for i, item := range items {
+		obj := item.(map[string]any)
+		if obj["status"] != statuses[i%len(statuses)] {
+			t.Errorf("Row %d: expected status=%s, got: %v", i, statuses[i%len(statuses)], obj["status"])
+		}
+		if obj["currency"] != "EUR" {
+			t.Errorf("Row %d: expected currency=EUR, got: %v", i, obj["currency"])
+		}
+	}
+}
+
+func TestDictionaryEncodingSkipsHighCardinalityColumns(t *testing.T) {
+	config := DefaultConfig()
+	config.DictionaryEncoding = true
+
+	data := make([]any, 5)
+	for i := range data {
+		data[i] = map[string]any{
+			"email":  fmt.Sprintf("user%d@example.com", i),
+			"active": i%2 == 0,
+		}
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if strings.Contains(toon, "&") {
+		t.Errorf("Expected no dictionaries for unique or non-string columns, got: %s", toon)
+	}
+}
+
+func TestDictionaryEncodingWithNestedColumns(t *testing.T) {
+	config := DefaultConfig()
+	config.DictionaryEncoding = true
+
+	data := []any{
+		map[string]any{"id": 1, "category": map[string]any{"name": "electronics"}, "note": nil},
+		map[string]any{"id": 2, "category": map[string]any{"name": "electronics"}, "note": "fragile, handle with care"},
+		map[string]any{"id": 3, "category": map[string]any{"name": "electronics"}, "note": nil},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.Contains(toon, "&category.name: electronics") {
+		t.Errorf("Expected dictionary for nested column, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	second := items[1].(map[string]any)
+	if second["category"].(map[string]any)["name"] != "electronics" {
+		t.Errorf("Expected category.name='electronics', got: %v", second["category"])
+	}
+	if second["note"] != "fragile, handle with care" {
+		t.Errorf("Expected escaped note to survive, got: %v", second["note"])
+	}
+	if items[0].(map[string]any)["note"] != nil {
+		t.Errorf("Expected nil note, got: %v", items[0].(map[string]any)["note"])
+	}
+}
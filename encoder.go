@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/b92c/gotoon/ast"
 )
 
 // Encoder handles encoding Go data structures to TOON format.
@@ -26,39 +29,59 @@ func NewEncoder(config *Config) *Encoder {
 	}
 }
 
-// Encode converts data to TOON format string.
+// Encode converts data to TOON format string. Values implementing
+// ToonMarshaler, encoding.TextMarshaler or json.Marshaler are encoded as the
+// value they marshal to, and structs, typed maps and typed slices are
+// converted as encoding/json would, using toon or json field tags. Configured
+// transformers are applied before encoding.
 func (e *Encoder) Encode(data any) (string, error) {
 	if str, ok := data.(string); ok && looksLikeJSON(str) {
 		var decoded any
 		if err := json.Unmarshal([]byte(str), &decoded); err == nil {
-			return e.valueToToon(decoded, 0), nil
+			data = decoded
 		}
 	}
 
-	return e.valueToToon(data, 0), nil
+	data, err := e.transform(data, "")
+	if err != nil {
+		return "", err
+	}
+	return e.valueToToon(data, 0, ""), nil
 }
 
-// valueToToon converts a value to TOON format with indentation.
-func (e *Encoder) valueToToon(value any, depth int) string {
+// valueToToon converts a value to TOON format with indentation. path is the
+// dotted key path of the value, used to look up configured comments.
+func (e *Encoder) valueToToon(value any, depth int, path string) string {
 	indent := strings.Repeat("  ", depth)
+	tablePath := joinPath(path, "items")
 
 	if slice, ok := value.([]any); ok {
 		if isSequentialArraySlice(slice) {
-			if e.flattener.HasNestedObjects(slice) {
+			if e.config.NormalizeObjects && len(slice) >= e.config.MinRowsForTable {
+				if normalized := e.flattener.FlattenNormalized(slice); len(normalized.References) > 0 {
+					return e.withComment(tablePath, depth, e.flattenedToToon(normalized, depth))
+				}
+			}
+
+			if isArrayOfObjects(slice) && e.flattener.HasNestedObjects(slice) {
 				flattened := e.flattener.Flatten(slice)
-				return e.flattenedToToon(flattened, depth)
+				return e.withComment(tablePath, depth, e.flattenedToToon(flattened, depth))
 			}
 
 			if e.isArrayOfUniformObjects(slice) {
-				return e.arrayOfObjectsToToon(slice, depth)
+				return e.withComment(tablePath, depth, e.arrayOfObjectsToToon(slice, depth))
 			}
 
-			return e.sequentialArrayToToon(slice, depth)
+			if e.config.SparseTables && isArrayOfObjects(slice) && len(slice) >= e.config.MinRowsForTable {
+				return e.withComment(tablePath, depth, e.flattenedToToon(e.flattener.Flatten(slice), depth))
+			}
+
+			return e.sequentialArrayToToon(slice, depth, path)
 		}
 	}
 
 	if m, ok := value.(map[string]any); ok {
-		return e.associativeArrayToToon(m, depth)
+		return e.associativeArrayToToon(m, depth, path)
 	}
 
 	return indent + e.escapeScalar(value)
@@ -66,25 +89,7 @@ func (e *Encoder) valueToToon(value any, depth int) string {
 
 // flattenedToToon converts flattened data to TOON table format.
 func (e *Encoder) flattenedToToon(flattened *FlattenedData, depth int) string {
-	indent := strings.Repeat("  ", depth)
-
-	formattedCols := make([]string, len(flattened.Columns))
-	for i, col := range flattened.Columns {
-		formattedCols[i] = e.config.formatKey(col)
-	}
-
-	header := fmt.Sprintf("%sitems[%d]{%s}:", indent, len(flattened.Rows), strings.Join(formattedCols, ","))
-
-	rowLines := make([]string, len(flattened.Rows))
-	for i, row := range flattened.Rows {
-		cells := make([]string, len(row))
-		for j, cell := range row {
-			cells[j] = e.escapeScalar(cell)
-		}
-		rowLines[i] = indent + "  " + strings.Join(cells, ",")
-	}
-
-	return header + "\n" + strings.Join(rowLines, "\n")
+	return e.tableToToon("items", flattened, depth)
 }
 
 // arrayOfObjectsToToon converts an array of uniform objects to TOON table format.
@@ -95,49 +100,146 @@ func (e *Encoder) arrayOfObjectsToToon(arr []any, depth int) string {
 
 	firstObj, ok := arr[0].(map[string]any)
 	if !ok {
-		return e.sequentialArrayToToon(arr, depth)
+		return e.sequentialArrayToToon(arr, depth, "")
 	}
 
-	fields := make([]string, 0, len(firstObj))
-	for key := range firstObj {
-		fields = append(fields, key)
-	}
+	fields := sortedKeys(firstObj)
 
-	formattedFields := make([]string, len(fields))
-	for i, f := range fields {
-		formattedFields[i] = e.config.formatKey(f)
+	rows := make([][]any, len(arr))
+	for i, item := range arr {
+		obj, _ := item.(map[string]any)
+
+		cells := make([]any, len(fields))
+		for j, field := range fields {
+			cells[j] = obj[field]
+		}
+		rows[i] = cells
 	}
 
+	return e.tableToToon("items", &FlattenedData{Columns: fields, Rows: rows}, depth)
+}
+
+// tableToToon renders flattened data as a TOON table with the given name.
+// Reference tables are rendered inside the table, before its rows.
+func (e *Encoder) tableToToon(name string, data *FlattenedData, depth int) string {
 	indent := strings.Repeat("  ", depth)
-	header := fmt.Sprintf("%sitems[%d]{%s}:", indent, len(arr), strings.Join(formattedFields, ","))
+	columns, rows := data.Columns, data.Rows
 
-	rows := make([]string, len(arr))
-	for i, item := range arr {
-		obj, ok := item.(map[string]any)
-		if !ok {
-			continue
+	references := make(map[string]bool, len(data.References))
+	for _, ref := range data.References {
+		references[ref.Column] = true
+	}
+
+	optional := make([]bool, len(columns))
+	if e.config.SparseTables {
+		for _, missing := range data.Missing {
+			for j, m := range missing {
+				optional[j] = optional[j] || m
+			}
 		}
+	}
 
-		cells := make([]string, len(fields))
-		for j, field := range fields {
-			cells[j] = e.escapeScalar(obj[field])
+	jsonColumns := make([]bool, len(columns))
+	formattedCols := make([]string, len(columns))
+	for i, col := range columns {
+		formattedCols[i] = e.config.formatKey(col)
+		if references[col] {
+			formattedCols[i] = referencePrefix + formattedCols[i]
+		}
+		if optional[i] {
+			formattedCols[i] += optionalSuffix
+		}
+		if isJSONColumn(rows, i) {
+			jsonColumns[i] = true
+			formattedCols[i] += ":" + jsonColumnType
+		} else if e.config.TypedHeaders && !references[col] {
+			if typ := columnType(rows, i); typ != "" {
+				formattedCols[i] += ":" + typ
+			}
+		}
+	}
+
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		cells[i] = make([]string, len(row))
+		for j, cell := range row {
+			switch {
+			case optional[j] && data.Missing[i][j]:
+				cells[i][j] = ""
+			case optional[j] && cell == nil:
+				cells[i][j] = "null"
+			case jsonColumns[j] && cell != nil:
+				cells[i][j] = e.jsonCell(cell)
+			default:
+				cells[i][j] = e.escapeScalar(cell)
+			}
 		}
-		rows[i] = indent + "  " + strings.Join(cells, ",")
 	}
 
-	return header + "\n" + strings.Join(rows, "\n")
+	attributes := ""
+	if e.config.HoistConstantColumns {
+		var constants []string
+		formattedCols, rows, cells, constants = hoistConstantColumns(formattedCols, rows, cells)
+		if len(constants) > 0 {
+			attributes = "@{" + strings.Join(constants, ",") + "}"
+		}
+	}
+
+	header := fmt.Sprintf("%s%s[%d]{%s}%s:", indent, name, len(rows), strings.Join(formattedCols, ","), attributes)
+	lines := []string{header}
+
+	if e.config.DictionaryEncoding {
+		for j, dict := range e.buildDictionaries(rows, cells) {
+			if dict == nil {
+				continue
+			}
+			lines = append(lines, indent+"  &"+ast.ParseColumn(formattedCols[j]).Name+": "+strings.Join(dict.values, ","))
+			dict.apply(cells, j)
+		}
+	}
+
+	for _, ref := range data.References {
+		refName := referencePrefix + e.config.formatKey(ref.Column)
+		lines = append(lines, e.tableToToon(refName, ref.Data, depth+1))
+	}
+
+	for _, row := range cells {
+		lines = append(lines, indent+"  "+strings.Join(row, ","))
+	}
+
+	return strings.Join(lines, "\n")
 }
 
-// sequentialArrayToToon converts a sequential array to TOON format.
-func (e *Encoder) sequentialArrayToToon(arr []any, depth int) string {
+// sequentialArrayToToon converts a sequential array to a TOON list where every
+// item starts with "- ". Nested items continue on lines indented below the dash.
+// Items are addressed by their index for comments, e.g. "tags.0".
+func (e *Encoder) sequentialArrayToToon(arr []any, depth int, path string) string {
 	indent := strings.Repeat("  ", depth)
-	lines := make([]string, len(arr))
+	lines := []string{}
 
 	for i, item := range arr {
+		itemPath := joinPath(path, strconv.Itoa(i))
+		lines = append(lines, e.commentLines(itemPath, depth)...)
+
+		var rendered string
 		if isScalar(item) {
-			lines[i] = indent + e.escapeScalar(item)
+			rendered = indent + "  " + escapeListScalar(e.escapeScalar(item))
+		} else {
+			rendered = e.valueToToon(item, depth+1, itemPath)
+		}
+
+		// Comments leading the item's body are written above the dash.
+		body := strings.Split(rendered, "\n")
+		for len(body) > 1 && strings.HasPrefix(body[0], indent+"  #") {
+			lines = append(lines, indent+strings.TrimPrefix(body[0], indent+"  "))
+			body = body[1:]
+		}
+
+		content := strings.TrimPrefix(strings.Join(body, "\n"), indent+"  ")
+		if strings.TrimSpace(content) == "" {
+			lines = append(lines, indent+"-")
 		} else {
-			lines[i] = e.valueToToon(item, depth)
+			lines = append(lines, indent+"- "+content)
 		}
 	}
 
@@ -145,11 +247,12 @@ func (e *Encoder) sequentialArrayToToon(arr []any, depth int) string {
 }
 
 // associativeArrayToToon converts a map to TOON format.
-func (e *Encoder) associativeArrayToToon(m map[string]any, depth int) string {
+func (e *Encoder) associativeArrayToToon(m map[string]any, depth int, path string) string {
 	indent := strings.Repeat("  ", depth)
 	lines := []string{}
 
-	for key, val := range m {
+	for _, key := range sortedKeys(m) {
+		val := m[key]
 		if e.config.shouldOmitKey(key) {
 			continue
 		}
@@ -167,18 +270,43 @@ func (e *Encoder) associativeArrayToToon(m map[string]any, depth int) string {
 		}
 
 		formattedKey := e.config.formatKey(key)
+		lines = append(lines, e.commentLines(joinPath(path, key), depth)...)
 
 		if isScalar(val) {
 			lines = append(lines, indent+formattedKey+": "+e.escapeScalar(val))
 		} else {
 			lines = append(lines, indent+formattedKey+":")
-			lines = append(lines, e.valueToToon(val, depth+1))
+			lines = append(lines, e.valueToToon(val, depth+1, joinPath(path, key)))
 		}
 	}
 
 	return strings.Join(lines, "\n")
 }
 
+// commentLines renders the comment configured for path as "# " lines.
+func (e *Encoder) commentLines(path string, depth int) []string {
+	text, ok := e.config.Comments[path]
+	if !ok {
+		return nil
+	}
+
+	indent := strings.Repeat("  ", depth)
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, strings.TrimRight(indent+"# "+line, " "))
+	}
+	return lines
+}
+
+// withComment prefixes rendered with the comment configured for path.
+func (e *Encoder) withComment(path string, depth int, rendered string) string {
+	lines := e.commentLines(path, depth)
+	if len(lines) == 0 {
+		return rendered
+	}
+	return strings.Join(append(lines, rendered), "\n")
+}
+
 // escapeScalar converts a scalar value to its string representation.
 func (e *Encoder) escapeScalar(v any) string {
 	if v == nil {
@@ -221,26 +349,15 @@ func (e *Encoder) escapeScalar(v any) string {
 	case string:
 		s := val
 
-		if e.config.DateFormat != "" && looksLikeISODate(s) {
-			if t, err := time.Parse(time.RFC3339, s); err == nil {
-				return t.Format(e.config.DateFormat)
-			}
-			if t, err := time.Parse("2006-01-02T15:04:05", s); err == nil {
-				return t.Format(e.config.DateFormat)
-			}
-			if t, err := time.Parse("2006-01-02", s); err == nil {
+		if e.config.DateFormat != "" {
+			if t, ok := parseISODate(s); ok {
 				return t.Format(e.config.DateFormat)
 			}
 		}
 
 		s = strings.TrimSpace(regexp.MustCompile(`\s+`).ReplaceAllString(s, " "))
 
-		if e.config.EscapeStyle == "backslash" {
-			s = strings.ReplaceAll(s, "\\", "\\\\")
-			s = strings.ReplaceAll(s, ",", "\\,")
-			s = strings.ReplaceAll(s, ":", "\\:")
-			s = strings.ReplaceAll(s, "\n", "\\n")
-		}
+		s = e.escapeText(s)
 
 		if e.config.TruncateStrings > 0 && len(s) > e.config.TruncateStrings {
 			s = s[:e.config.TruncateStrings] + "..."
@@ -250,7 +367,7 @@ func (e *Encoder) escapeScalar(v any) string {
 
 	case []any, map[string]any:
 		if bytes, err := json.Marshal(val); err == nil {
-			return string(bytes)
+			return e.escapeText(string(bytes))
 		}
 		return "[]"
 
@@ -259,6 +376,14 @@ func (e *Encoder) escapeScalar(v any) string {
 	}
 }
 
+// escapeText escapes characters that separate cells and keys.
+func (e *Encoder) escapeText(s string) string {
+	if e.config.EscapeStyle == "backslash" {
+		return escapeBackslash(s)
+	}
+	return s
+}
+
 // isArrayOfUniformObjects checks if all items are maps with the same keys.
 func (e *Encoder) isArrayOfUniformObjects(arr []any) bool {
 	if len(arr) < e.config.MinRowsForTable {
@@ -305,6 +430,45 @@ func isScalar(v any) bool {
 	}
 }
 
+// escapeBackslash escapes backslashes, commas, colons and newlines.
+func escapeBackslash(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, ",", "\\,")
+	s = strings.ReplaceAll(s, ":", "\\:")
+	s = strings.ReplaceAll(s, "\n", "\\n")
+	return s
+}
+
+// escapeListScalar escapes a leading "-" in the text of a scalar list item,
+// which would otherwise read as a nested list item.
+func escapeListScalar(s string) string {
+	if s == "-" || strings.HasPrefix(s, "- ") {
+		return "\\" + s
+	}
+	return s
+}
+
+// isArrayOfObjects checks if the array is non-empty and every item is a map.
+func isArrayOfObjects(arr []any) bool {
+	if len(arr) == 0 {
+		return false
+	}
+	for _, item := range arr {
+		if _, ok := item.(map[string]any); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// joinPath appends key to a dotted key path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
 func isSequentialArraySlice(v []any) bool {
 	// In Go, []any is always sequential
 	return true
@@ -320,11 +484,35 @@ func looksLikeISODate(s string) bool {
 	return matched
 }
 
+// parseISODate parses an RFC 3339 timestamp, a timestamp without a zone or a
+// plain date.
+func parseISODate(s string) (time.Time, bool) {
+	if !looksLikeISODate(s) {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
 func safeKey(k string) string {
 	re := regexp.MustCompile(`[^A-Za-z0-9_\-\.]`)
 	return re.ReplaceAllString(k, "")
 }
 
+// sortedKeys returns the keys of m in sorted order so output is deterministic.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func stringSlicesEqual(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -1,9 +1,18 @@
 package gotoon
 
 import (
+	"encoding/json"
 	"strings"
 )
 
+const (
+	// referencePrefix marks reference columns and reference table headers.
+	referencePrefix = "*"
+
+	// optionalSuffix marks sparse table columns that some items do not have.
+	optionalSuffix = "?"
+)
+
 // ArrayFlattener handles flattening of nested objects in arrays.
 type ArrayFlattener struct {
 	maxDepth int
@@ -16,8 +25,20 @@ func NewArrayFlattener(maxDepth int) *ArrayFlattener {
 
 // FlattenedData represents flattened array data with columns and rows.
 type FlattenedData struct {
-	Columns []string
-	Rows    [][]any
+	Columns    []string
+	Rows       [][]any
+	References []*ReferenceTable
+
+	// Missing marks cells whose path does not exist in the source item, as
+	// opposed to existing with a nil value. It is nil when no item was missing data.
+	Missing [][]bool
+}
+
+// ReferenceTable holds the distinct nested objects extracted from a column.
+// Rows of the parent table store the index of their object in Data.Rows.
+type ReferenceTable struct {
+	Column string
+	Data   *FlattenedData
 }
 
 // Flatten converts an array of objects with nested structures into a flat table format.
@@ -28,15 +49,35 @@ func (f *ArrayFlattener) Flatten(items []any) *FlattenedData {
 
 	columns := f.extractColumns(items)
 	rows := make([][]any, len(items))
+	missing := make([][]bool, len(items))
+	hasMissing := false
 
 	for i, item := range items {
-		rows[i] = f.flattenRow(item, columns)
+		rows[i], missing[i] = f.flattenRow(item, columns)
+		for _, m := range missing[i] {
+			hasMissing = hasMissing || m
+		}
 	}
 
-	return &FlattenedData{
+	flattened := &FlattenedData{
 		Columns: columns,
 		Rows:    rows,
 	}
+	if hasMissing {
+		flattened.Missing = missing
+	}
+
+	return flattened
+}
+
+// FlattenNormalized works like Flatten, but first extracts nested objects that
+// repeat across items into reference tables. The parent table keeps an index
+// column pointing into the reference table instead of repeating every field.
+func (f *ArrayFlattener) FlattenNormalized(items []any) *FlattenedData {
+	normalized, references := f.extractReferences(items)
+	flattened := f.Flatten(normalized)
+	flattened.References = references
+	return flattened
 }
 
 // HasNestedObjects checks if any item in the array has nested objects.
@@ -56,6 +97,104 @@ func (f *ArrayFlattener) HasNestedObjects(items []any) bool {
 	return false
 }
 
+// extractReferences replaces repeated nested objects with indexes into
+// reference tables. Only top-level keys holding objects in every item where
+// they are set, with at least one repeated object, are extracted.
+func (f *ArrayFlattener) extractReferences(items []any) ([]any, []*ReferenceTable) {
+	var references []*ReferenceTable
+	normalized := items
+
+	for _, key := range f.extractKeys(items) {
+		unique, indexes, ok := f.dedupeObjects(normalized, key)
+		if !ok {
+			continue
+		}
+
+		if len(references) == 0 {
+			normalized = make([]any, len(items))
+			for i, item := range items {
+				normalized[i] = copyMap(item.(map[string]any))
+			}
+		}
+
+		for i, item := range normalized {
+			if idx, exists := indexes[i]; exists {
+				item.(map[string]any)[key] = idx
+			}
+		}
+
+		references = append(references, &ReferenceTable{Column: key, Data: f.Flatten(unique)})
+	}
+
+	return normalized, references
+}
+
+// dedupeObjects collects the distinct objects stored under key and the index of
+// each item's object. It reports false when key holds non-object values or
+// when no object repeats.
+func (f *ArrayFlattener) dedupeObjects(items []any, key string) ([]any, map[int]int, bool) {
+	var unique []any
+	seen := make(map[string]int)
+	indexes := make(map[int]int)
+
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, nil, false
+		}
+
+		value, exists := m[key]
+		if !exists || value == nil {
+			continue
+		}
+
+		nested, ok := value.(map[string]any)
+		if !ok || len(nested) == 0 {
+			return nil, nil, false
+		}
+
+		encoded, err := json.Marshal(nested)
+		if err != nil {
+			return nil, nil, false
+		}
+
+		idx, exists := seen[string(encoded)]
+		if !exists {
+			idx = len(unique)
+			seen[string(encoded)] = idx
+			unique = append(unique, nested)
+		}
+		indexes[i] = idx
+	}
+
+	if len(unique) == 0 || len(unique) == len(indexes) {
+		return nil, nil, false
+	}
+
+	return unique, indexes, true
+}
+
+// extractKeys returns the top-level keys of all object items in first-seen order.
+func (f *ArrayFlattener) extractKeys(items []any) []string {
+	keySet := make(map[string]bool)
+	var keys []string
+
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, key := range sortedKeys(m) {
+			if !keySet[key] {
+				keySet[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
 // extractColumns walks through all items and extracts column paths.
 func (f *ArrayFlattener) extractColumns(items []any) []string {
 	columnSet := make(map[string]bool)
@@ -74,7 +213,8 @@ func (f *ArrayFlattener) extractColumns(items []any) []string {
 
 // walkItem recursively walks an item to extract column paths.
 func (f *ArrayFlattener) walkItem(item map[string]any, prefix string, columnSet map[string]bool, columns *[]string, depth int) {
-	for key, value := range item {
+	for _, key := range sortedKeys(item) {
+		value := item[key]
 		path := key
 		if prefix != "" {
 			path = prefix + "." + key
@@ -93,36 +233,41 @@ func (f *ArrayFlattener) walkItem(item map[string]any, prefix string, columnSet
 }
 
 // flattenRow converts a single item into a flat row based on column paths.
-func (f *ArrayFlattener) flattenRow(item any, columns []string) []any {
+// The second result marks columns whose path does not exist in the item.
+func (f *ArrayFlattener) flattenRow(item any, columns []string) ([]any, []bool) {
 	row := make([]any, len(columns))
+	missing := make([]bool, len(columns))
 
 	for i, col := range columns {
-		row[i] = f.getByPath(item, col)
+		value, exists := f.lookupPath(item, col)
+		row[i] = value
+		missing[i] = !exists
 	}
 
-	return row
+	return row, missing
 }
 
-// getByPath retrieves a value from nested maps using a dot-separated path.
-func (f *ArrayFlattener) getByPath(data any, path string) any {
+// lookupPath retrieves a value from nested maps using a dot-separated path and
+// reports whether the full path exists.
+func (f *ArrayFlattener) lookupPath(data any, path string) (any, bool) {
 	segments := strings.Split(path, ".")
 
 	current := data
 	for _, segment := range segments {
 		m, ok := current.(map[string]any)
 		if !ok {
-			return nil
+			return nil, false
 		}
 
 		value, exists := m[segment]
 		if !exists {
-			return nil
+			return nil, false
 		}
 
 		current = value
 	}
 
-	return current
+	return current, true
 }
 
 // isSequentialArray checks if a value represents a sequential array (not a map).
@@ -134,3 +279,12 @@ func isSequentialArray(v any) bool {
 	_ = slice
 	return false
 }
+
+// copyMap returns a shallow copy of m.
+func copyMap(m map[string]any) map[string]any {
+	result := make(map[string]any, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,251 @@
+package gotoon
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/b92c/gotoon/ast"
+)
+
+// FormatOptions controls how Format rewrites a TOON document.
+type FormatOptions struct {
+	// PreserveOrder keeps object keys and table columns in source order
+	// instead of sorting them the way the encoder does.
+	PreserveOrder bool
+}
+
+// Format parses src and re-emits it as canonical TOON: two-space indentation,
+// sorted keys and columns and minimal escaping. Table cells escape only
+// commas, backslashes and newlines, plus colons in a row's first and last
+// cells, and "key: value" values only backslashes and newlines. Values are only rewritten when they decode to the same result,
+// and comments stay above the entry they precede. Malformed input is reported as a *SyntaxError.
+func Format(src string, opts *FormatOptions) (string, error) {
+	if opts == nil {
+		opts = &FormatOptions{}
+	}
+
+	doc, err := ast.Parse(src)
+	if err != nil {
+		return "", err
+	}
+
+	f := &formatter{opts: opts, decoder: NewDecoder(nil)}
+	doc.Nodes = f.block(doc.Nodes)
+
+	printer := &ast.Printer{}
+	return printer.Print(doc), nil
+}
+
+// formatter rewrites a syntax tree into canonical form.
+type formatter struct {
+	opts    *FormatOptions
+	decoder *Decoder
+}
+
+// block canonicalizes a block of nodes and returns it in canonical order.
+func (f *formatter) block(nodes []ast.Node) []ast.Node {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.KeyValue:
+			n.Value = f.value(n.Value)
+		case *ast.Object:
+			n.Body = f.block(n.Body)
+		case *ast.ListItem:
+			n.Body = f.block(n.Body)
+		case *ast.Scalar:
+			n.Value = escapeListScalar(canonicalText(n.Value, f.decoder.parseValue, escapeBackslash))
+		case *ast.Table:
+			f.table(n)
+		}
+	}
+
+	if f.opts.PreserveOrder {
+		return nodes
+	}
+
+	// Comments move along with the entry that follows them.
+	var groups [][]ast.Node
+	var pending []ast.Node
+	for _, n := range nodes {
+		pending = append(pending, n)
+		if _, ok := n.(*ast.Comment); !ok {
+			groups = append(groups, pending)
+			pending = nil
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return nodeKey(groups[i][len(groups[i])-1]) < nodeKey(groups[j][len(groups[j])-1])
+	})
+
+	sorted := make([]ast.Node, 0, len(nodes))
+	for _, group := range groups {
+		sorted = append(sorted, group...)
+	}
+	return append(sorted, pending...)
+}
+
+// table canonicalizes a table: columns in canonical order, cells and
+// attributes re-escaped and dictionaries and reference tables sorted.
+func (f *formatter) table(table *ast.Table) {
+	order := make([]int, len(table.Columns))
+	for i := range order {
+		order[i] = i
+	}
+	if !f.opts.PreserveOrder {
+		sort.SliceStable(order, func(a, b int) bool {
+			return table.Columns[order[a]].Name < table.Columns[order[b]].Name
+		})
+	}
+
+	columns := make([]ast.Column, len(order))
+	for i, col := range order {
+		columns[i] = table.Columns[col]
+	}
+
+	for _, attr := range table.Attributes {
+		if attr.Column.Type == jsonColumnType || isScalarColumnType(attr.Column.Type) {
+			attr.Value = f.cell(attr.Value, attr.Column.Type, escapeBackslash)
+		} else {
+			attr.Value = canonicalText(attr.Value, f.decoder.parseValue, escapeBackslash)
+		}
+	}
+
+	for _, dict := range table.Dictionaries {
+		for _, cell := range dict.Values {
+			cell.Raw = f.cell(cell.Raw, "", escapeCell)
+		}
+	}
+
+	for _, ref := range table.References {
+		f.table(ref)
+	}
+
+	for _, row := range table.Rows {
+		cells := make([]*ast.Cell, len(order))
+		for i, col := range order {
+			escape := escapeCell
+			if i == 0 || i == len(order)-1 {
+				escape = escapeEdgeCell
+			}
+			if col < len(row.Cells) {
+				cells[i] = row.Cells[col]
+				cells[i].Raw = f.cell(cells[i].Raw, table.Columns[col].Type, escape)
+			} else {
+				cells[i] = &ast.Cell{Pos: row.Pos}
+			}
+		}
+		row.Cells = cells
+	}
+	table.Columns = columns
+
+	if !f.opts.PreserveOrder {
+		sort.SliceStable(table.Attributes, func(i, j int) bool {
+			return table.Attributes[i].Column.String() < table.Attributes[j].Column.String()
+		})
+		sort.SliceStable(table.Dictionaries, func(i, j int) bool {
+			return table.Dictionaries[i].Column < table.Dictionaries[j].Column
+		})
+		sort.SliceStable(table.References, func(i, j int) bool {
+			return table.References[i].Name < table.References[j].Name
+		})
+	}
+}
+
+// value canonicalizes the text of a "key: value" line.
+func (f *formatter) value(raw string) string {
+	return canonicalText(raw, f.decoder.parseValue, escapeValue)
+}
+
+// cell canonicalizes the text of a table cell or attribute of type kind,
+// re-escaping it with escape.
+func (f *formatter) cell(raw, kind string, escape func(string) string) string {
+	raw = strings.TrimSpace(raw)
+
+	if kind == jsonColumnType {
+		expected, err := decodeJSONCell(raw)
+		candidate := escape(unescapeCell(raw))
+		if actual, _ := decodeJSONCell(candidate); err == nil && reflect.DeepEqual(expected, actual) {
+			return candidate
+		}
+		return raw
+	}
+
+	if isScalarColumnType(kind) {
+		return canonicalText(raw, func(s string) any {
+			value, _ := f.decoder.parseTyped(s, kind)
+			return value
+		}, escape)
+	}
+
+	return canonicalText(raw, f.decoder.parseCell, escape)
+}
+
+// canonicalText re-escapes raw text with escape. Numbers, booleans and nulls
+// keep their text, and text is only rewritten when it decodes to the same
+// value.
+func canonicalText(raw string, decode func(string) any, escape func(string) string) string {
+	raw = strings.TrimSpace(raw)
+
+	value := decode(raw)
+	str, ok := value.(string)
+	if !ok {
+		return raw
+	}
+
+	if candidate := escape(str); decode(candidate) == value {
+		return candidate
+	}
+	return raw
+}
+
+// escapeValue escapes the backslashes and newlines of a "key: value" value.
+// Nothing else needs escaping after the key.
+func escapeValue(s string) string {
+	return escapeChars(s, "")
+}
+
+// escapeCell escapes the backslashes, newlines and commas of a table cell.
+func escapeCell(s string) string {
+	return escapeChars(s, ",")
+}
+
+// escapeEdgeCell escapes the first or last cell of a row, which also needs
+// its colons escaped: unescaped, a row could read as a "&column:" dictionary
+// line or a "*name[N]{...}:" reference table header.
+func escapeEdgeCell(s string) string {
+	return escapeChars(s, ",:")
+}
+
+// escapeChars escapes backslashes, newlines and the characters in extra.
+func escapeChars(s, extra string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case strings.ContainsRune(extra, r):
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// nodeKey returns the key used to order object entries.
+func nodeKey(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.KeyValue:
+		return n.Key
+	case *ast.Object:
+		return n.Key
+	case *ast.Table:
+		return n.Name
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,155 @@
+package gotoon
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const messyToon = `user:
+    name: Alice\, Smith
+    role: admin
+    email: alice@example.com
+tags:
+    -   b
+    - a\-1
+orders:
+    items[2]{ total , id , customer.name }:
+        9.50 , 1 , Alice
+        12,2,Bob\: Jr
+count: 2`
+
+func TestFormatCanonicalizesDocument(t *testing.T) {
+	formatted, err := Format(messyToon, nil)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := `count: 2
+orders:
+  items[2]{customer.name,id,total}:
+    Alice,1,9.50
+    Bob\: Jr,2,12
+tags:
+  - b
+  - a\\-1
+user:
+  email: alice@example.com
+  name: Alice, Smith
+  role: admin`
+
+	if formatted != expected {
+		t.Errorf("Unexpected formatting.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+
+	again, err := Format(formatted, nil)
+	if err != nil {
+		t.Fatalf("Format of formatted output failed: %v", err)
+	}
+	if again != formatted {
+		t.Errorf("Format is not idempotent.\nFirst:\n%s\nSecond:\n%s", formatted, again)
+	}
+}
+
+func TestFormatEscapesMinimally(t *testing.T) {
+	src := "k: a\\:b\\, c\nitems[2]{a,b}:\n  x\\:y,\"1\\,2\"\n  p,r\\\\s\n"
+
+	formatted, err := Format(src, nil)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := "items[2]{a,b}:\n  x\\:y,\"1\\,2\"\n  p,r\\\\s\nk: a:b, c"
+	if formatted != expected {
+		t.Errorf("Unexpected formatting.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+
+	want, _ := Decode(src)
+	got, err := Decode(formatted)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Formatted document decodes differently.\nExpected: %v\nGot:      %v", want, got)
+	}
+}
+
+func TestFormatKeepsRowsThatLookLikeOtherLines(t *testing.T) {
+	src := "items[2]{a,b,c}:\n  &z\\: 1,m\\:n,q\n  *c[1]{a}\\:,x,y\\:\n"
+
+	formatted, err := Format(src, nil)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := "items[2]{a,b,c}:\n  &z\\: 1,m:n,q\n  *c[1]{a}\\:,x,y\\:"
+	if formatted != expected {
+		t.Errorf("Unexpected formatting.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+
+	again, err := Format(formatted, nil)
+	if err != nil || again != formatted {
+		t.Errorf("Format is not idempotent: %v\n%s", err, again)
+	}
+
+	want, _ := Decode(src)
+	got, err := Decode(formatted)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Formatted document decodes differently.\nExpected: %v\nGot:      %v", want, got)
+	}
+	if rows, _ := got["items"].([]any); len(rows) != 2 {
+		t.Errorf("Expected 2 rows, got %v", got["items"])
+	}
+}
+
+func TestFormatPreservesDecodedValues(t *testing.T) {
+	config := DefaultConfig()
+	config.DictionaryEncoding = true
+	config.HoistConstantColumns = true
+	config.NormalizeObjects = true
+	config.SparseTables = true
+
+	customer := map[string]any{"id": "c1", "name": "Alice"}
+	data := map[string]any{
+		"orders": []any{
+			map[string]any{"id": 1, "status": "open", "customer": customer, "tags": []any{"x, y"}},
+			map[string]any{"id": 2, "status": "open", "customer": customer},
+			map[string]any{"id": 3, "status": "closed", "customer": customer, "note": "a: b"},
+		},
+		"mixed": []any{1, "two", map[string]any{"b": 2, "a": 1}},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	formatted, err := Format(toon, nil)
+	if err != nil {
+		t.Fatalf("Format failed: %v\n%s", err, toon)
+	}
+
+	before, _ := Decode(toon)
+	after, _ := Decode(formatted)
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("Format changed decoded values.\nBefore: %+v\nAfter:  %+v", before, after)
+	}
+}
+
+func TestFormatPreserveOrder(t *testing.T) {
+	formatted, err := Format("b: 1\na: 2\nitems[1]{z,y}:\n  1,2", &FormatOptions{PreserveOrder: true})
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	expected := "b: 1\na: 2\nitems[1]{z,y}:\n  1,2"
+	if formatted != expected {
+		t.Errorf("Expected source order to be kept.\nExpected:\n%s\nGot:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatRejectsMalformedInput(t *testing.T) {
+	_, err := Format("items[2]{id,name}:\n  1,Alice\n  2", nil)
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 3 {
+		t.Errorf("Expected syntax error on line 3, got: %v", err)
+	}
+}
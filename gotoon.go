@@ -20,43 +20,49 @@ func Decode(toon string) (map[string]any, error) {
 	return defaultDecoder.Decode(toon)
 }
 
-// Diff estimates token savings between JSON and TOON formats.
-// Returns a map with json_chars, toon_chars, saved_chars, and savings_percent.
+// Diff estimates token savings between JSON and TOON formats using the default
+// encoder. Returns a map with json_chars, toon_chars, saved_chars,
+// savings_percent, json_tokens_est and toon_tokens_est; counts are zero when
+// data can't be encoded.
 func Diff(data any) map[string]any {
+	diff, _ := defaultEncoder.Diff(data)
+	return diff
+}
+
+// Diff estimates token savings between JSON and this encoder's TOON output, as
+// the package-level Diff does, and reports encoding errors.
+func (e *Encoder) Diff(data any) (map[string]any, error) {
+	diff := map[string]any{
+		"json_chars":      0,
+		"toon_chars":      0,
+		"saved_chars":     0,
+		"savings_percent": 0.0,
+		"json_tokens_est": 0,
+		"toon_tokens_est": 0,
+	}
+
 	jsonBytes, err := json.Marshal(data)
 	if err != nil {
-		return map[string]any{
-			"json_chars":      0,
-			"toon_chars":      0,
-			"saved_chars":     0,
-			"savings_percent": 0.0,
-		}
+		return diff, err
 	}
+	diff["json_chars"] = len(jsonBytes)
+	diff["json_tokens_est"] = EstimateTokens(string(jsonBytes))
 
-	toon, err := Encode(data)
+	toon, err := e.Encode(data)
 	if err != nil {
-		return map[string]any{
-			"json_chars":      len(jsonBytes),
-			"toon_chars":      0,
-			"saved_chars":     0,
-			"savings_percent": 0.0,
-		}
+		return diff, err
 	}
 
 	jsonLen := len(jsonBytes)
 	toonLen := len(toon)
 	saved := jsonLen - toonLen
-	savingsPercent := 0.0
 	if jsonLen > 0 {
-		savingsPercent = float64(saved) / float64(jsonLen) * 100
-	}
-
-	return map[string]any{
-		"json_chars":      jsonLen,
-		"toon_chars":      toonLen,
-		"saved_chars":     saved,
-		"savings_percent": savingsPercent,
+		diff["savings_percent"] = float64(saved) / float64(jsonLen) * 100
 	}
+	diff["toon_chars"] = toonLen
+	diff["saved_chars"] = saved
+	diff["toon_tokens_est"] = EstimateTokens(toon)
+	return diff, nil
 }
 
 // Only encodes only specific keys from the data.
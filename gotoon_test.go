@@ -340,6 +340,23 @@ func TestDiff(t *testing.T) {
 	if diff["savings_percent"].(float64) <= 0 {
 		t.Errorf("savings_percent should be positive")
 	}
+	if diff["toon_tokens_est"].(int) >= diff["json_tokens_est"].(int) {
+		t.Errorf("Expected fewer TOON tokens, got %v", diff)
+	}
+
+	config := DefaultConfig()
+	config.KeyAliases = map[string]string{"name": "n"}
+	aliased, err := NewEncoder(config).Diff(data)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if aliased["toon_chars"].(int) >= diff["toon_chars"].(int) {
+		t.Errorf("Expected the encoder config to apply, got %v", aliased)
+	}
+
+	if _, err := NewEncoder(nil).Diff(map[string]any{"ch": make(chan int)}); err == nil {
+		t.Errorf("Expected an error for data that can't be encoded")
+	}
 }
 
 func TestOnly(t *testing.T) {
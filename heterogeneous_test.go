@@ -0,0 +1,225 @@
+package gotoon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeMixedArrayAsList(t *testing.T) {
+	data := map[string]any{
+		"values": []any{
+			1,
+			"two, three",
+			map[string]any{"name": "Alice", "tags": []any{"admin", "dev"}},
+			[]any{4, 5},
+		},
+	}
+
+	toon, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "values:\n  - 1\n  - two\\, three\n  - name: Alice\n    tags:\n      - admin\n      - dev\n  - - 4\n    - 5"
+	if toon != expected {
+		t.Errorf("Unexpected list encoding.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	values, ok := decoded["values"].([]any)
+	if !ok || len(values) != 4 {
+		t.Fatalf("Expected 4 list items, got: %+v", decoded["values"])
+	}
+	if values[0] != 1 || values[1] != "two, three" {
+		t.Errorf("Expected scalar items to round-trip, got: %+v", values[:2])
+	}
+
+	obj := values[2].(map[string]any)
+	if obj["name"] != "Alice" {
+		t.Errorf("Expected name='Alice', got: %v", obj["name"])
+	}
+	if tags := obj["tags"].([]any); len(tags) != 2 || tags[1] != "dev" {
+		t.Errorf("Expected nested tags list, got: %+v", obj["tags"])
+	}
+
+	nested := values[3].([]any)
+	if len(nested) != 2 || nested[0] != 4 || nested[1] != 5 {
+		t.Errorf("Expected nested list [4 5], got: %+v", nested)
+	}
+}
+
+func TestNonUniformObjectsDecodeAsSeparateItems(t *testing.T) {
+	data := []any{
+		map[string]any{"id": 1, "name": "Alice", "email": "alice@example.com"},
+		map[string]any{"id": 2, "name": "Bob"},
+	}
+
+	toon, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.HasPrefix(toon, "- email: alice@example.com\n  id: 1") {
+		t.Errorf("Expected list items, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("Expected 2 items, got: %+v", items)
+	}
+
+	second := items[1].(map[string]any)
+	if second["name"] != "Bob" {
+		t.Errorf("Expected name='Bob', got: %v", second["name"])
+	}
+	if _, exists := second["email"]; exists {
+		t.Errorf("Second item should not have email, got: %+v", second)
+	}
+}
+
+func TestListOfTables(t *testing.T) {
+	data := map[string]any{
+		"pages": []any{
+			[]any{
+				map[string]any{"id": 1, "name": "A"},
+				map[string]any{"id": 2, "name": "B"},
+			},
+			"end",
+		},
+	}
+
+	toon, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	pages := decoded["pages"].([]any)
+	if len(pages) != 2 || pages[1] != "end" {
+		t.Fatalf("Expected two list items, got: %+v", pages)
+	}
+
+	table := pages[0].([]any)
+	if len(table) != 2 || table[1].(map[string]any)["name"] != "B" {
+		t.Errorf("Expected table inside list item, got: %+v", table)
+	}
+}
+
+func TestSparseTables(t *testing.T) {
+	config := DefaultConfig()
+	config.SparseTables = true
+
+	data := []any{
+		map[string]any{"id": 1, "name": "Alice", "email": "alice@example.com"},
+		map[string]any{"id": 2, "name": "Bob"},
+		map[string]any{"id": 3, "name": "Carol", "email": nil},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "items[3]{email?,id,name}:\n  alice@example.com,1,Alice\n  ,2,Bob\n  null,3,Carol"
+	if toon != expected {
+		t.Errorf("Unexpected sparse table.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	decoded, err := NewDecoder(config).Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got: %+v", items)
+	}
+
+	if items[0].(map[string]any)["email"] != "alice@example.com" {
+		t.Errorf("Expected email for first item, got: %+v", items[0])
+	}
+	if _, exists := items[1].(map[string]any)["email"]; exists {
+		t.Errorf("Expected email to be absent for second item, got: %+v", items[1])
+	}
+	if email, exists := items[2].(map[string]any)["email"]; !exists || email != nil {
+		t.Errorf("Expected explicit null email for third item, got: %+v", items[2])
+	}
+}
+
+func TestSparseTablesWithNestedColumns(t *testing.T) {
+	config := DefaultConfig()
+	config.SparseTables = true
+
+	data := []any{
+		map[string]any{"id": 1, "artist": map[string]any{"name": "DJ A"}},
+		map[string]any{"id": 2, "artist": map[string]any{"name": "DJ B", "genre": "Techno"}},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.Contains(toon, "artist.genre?") {
+		t.Errorf("Expected optional nested column, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	first := items[0].(map[string]any)["artist"].(map[string]any)
+	if _, exists := first["genre"]; exists {
+		t.Errorf("Expected genre to be absent for first artist, got: %+v", first)
+	}
+	second := items[1].(map[string]any)["artist"].(map[string]any)
+	if second["genre"] != "Techno" {
+		t.Errorf("Expected genre='Techno' for second artist, got: %+v", second)
+	}
+}
+
+func TestListItemsStartingWithDash(t *testing.T) {
+	data := map[string]any{"steps": []any{"- x", "-", "-5 degrees", 1}}
+
+	toon, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "steps:\n  - \\- x\n  - \\-\n  - -5 degrees\n  - 1"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, data) {
+		t.Errorf("Round trip mismatch.\nExpected: %v\nGot:      %v", data, decoded)
+	}
+
+	formatted, err := Format(toon, nil)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if formatted != toon {
+		t.Errorf("Expected Format to keep the escapes, got:\n%s", formatted)
+	}
+}
@@ -0,0 +1,229 @@
+// Package httptoon serves and accepts TOON over net/http. It negotiates
+// between JSON and TOON with the request's Accept header, writes responses
+// with the matching Content-Type and a "Vary: Accept" header, and decodes
+// request bodies sent as "Content-Type: application/toon".
+//
+// Handlers can call Respond directly, or keep writing JSON and wrap them with
+// Middleware, which converts JSON responses for clients that prefer TOON.
+// On the client side, Transport rewrites JSON responses from other services
+// as TOON.
+package httptoon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/b92c/gotoon"
+)
+
+// Media types negotiated by this package.
+const (
+	ContentType     = "application/toon"
+	JSONContentType = "application/json"
+)
+
+// ErrUnsupportedMediaType is returned by DecodeRequest for bodies that are
+// neither JSON nor TOON.
+var ErrUnsupportedMediaType = errors.New("httptoon: unsupported media type")
+
+// Codec negotiates, encodes and decodes HTTP bodies with one gotoon
+// configuration.
+type Codec struct {
+	encoder *gotoon.Encoder
+	decoder *gotoon.Decoder
+}
+
+// New creates a Codec with the given configuration. A nil config uses
+// gotoon.DefaultConfig.
+func New(config *gotoon.Config) *Codec {
+	return &Codec{
+		encoder: gotoon.NewEncoder(config),
+		decoder: gotoon.NewDecoder(config),
+	}
+}
+
+var defaultCodec = New(nil)
+
+// Respond writes data using the default Codec.
+func Respond(w http.ResponseWriter, r *http.Request, data any) error {
+	return defaultCodec.Respond(w, r, data)
+}
+
+// DecodeRequest decodes the request body into v using the default Codec.
+func DecodeRequest(r *http.Request, v any) error {
+	return defaultCodec.DecodeRequest(r, v)
+}
+
+// Middleware wraps next using the default Codec.
+func Middleware(next http.Handler) http.Handler {
+	return defaultCodec.Middleware(next)
+}
+
+// Respond writes data as TOON when the request prefers it and as JSON
+// otherwise, with status 200. Data of any type is accepted: JSON follows
+// encoding/json, and TOON is written by the Codec's encoder, so structs follow
+// toon tags and ToonMarshaler.
+func (c *Codec) Respond(w http.ResponseWriter, r *http.Request, data any) error {
+	addVary(w.Header(), "Accept")
+
+	if Negotiate(r) != ContentType {
+		body, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", JSONContentType)
+		_, err = w.Write(append(body, '\n'))
+		return err
+	}
+
+	toon, err := c.encoder.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", ContentType+"; charset=utf-8")
+	_, err = io.WriteString(w, toon+"\n")
+	return err
+}
+
+// DecodeRequest decodes a JSON or TOON request body into v, which must be a
+// pointer as for json.Unmarshal. TOON bodies are decoded with the Codec's
+// configuration and then stored in v following JSON rules, so struct json tags
+// apply. When v points to a slice, a body holding only a top-level table or
+// list fills the slice. A missing Content-Type is treated as JSON; other media
+// types return ErrUnsupportedMediaType.
+func (c *Codec) DecodeRequest(r *http.Request, v any) error {
+	header := r.Header.Get("Content-Type")
+	switch mediaType(header) {
+	case ContentType:
+	case JSONContentType:
+		return json.NewDecoder(r.Body).Decode(v)
+	default:
+		if header != "" {
+			return fmt.Errorf("%w %q", ErrUnsupportedMediaType, header)
+		}
+		return json.NewDecoder(r.Body).Decode(v)
+	}
+
+	src, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	decoded, err := c.decoder.Decode(string(src))
+	if err != nil {
+		return err
+	}
+
+	var data any = decoded
+	if target := reflect.ValueOf(v); target.Kind() == reflect.Pointer && target.Elem().Kind() == reflect.Slice {
+		data = unwrapItems(decoded)
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// Negotiate returns ContentType when the request's Accept header prefers TOON
+// and JSONContentType otherwise. Media ranges are ranked by q-value, then by
+// specificity ("application/toon" over "application/*" over "*/*"), then by
+// their order in the header. A missing header, or one accepting neither type,
+// yields JSON.
+func Negotiate(r *http.Request) string {
+	header := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		return JSONContentType
+	}
+
+	toon := match(header, ContentType)
+	jsonMatch := match(header, JSONContentType)
+	if toon.q > 0 && toon.better(jsonMatch) {
+		return ContentType
+	}
+	return JSONContentType
+}
+
+// acceptMatch describes the media range that matched a media type.
+type acceptMatch struct {
+	q           float64
+	specificity int
+	index       int
+}
+
+// better reports whether m ranks above other.
+func (m acceptMatch) better(other acceptMatch) bool {
+	if m.q != other.q {
+		return m.q > other.q
+	}
+	if m.specificity != other.specificity {
+		return m.specificity > other.specificity
+	}
+	return m.index < other.index
+}
+
+// match finds the most specific media range in an Accept header that covers
+// mediaType. A type no range covers gets q 0.
+func match(header, mediaType string) acceptMatch {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	best := acceptMatch{specificity: -1, index: -1}
+
+	for i, part := range strings.Split(header, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		specificity := -1
+		switch rangeType {
+		case mediaType:
+			specificity = 2
+		case typ + "/*":
+			specificity = 1
+		case "*/*":
+			specificity = 0
+		}
+		if specificity <= best.specificity {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		best = acceptMatch{q: q, specificity: specificity, index: i}
+	}
+
+	return best
+}
+
+// unwrapItems returns the list of a document holding only a top-level table
+// or list, which the decoder returns under the "items" key.
+func unwrapItems(data map[string]any) any {
+	if items, ok := data["items"].([]any); ok && len(data) == 1 {
+		return items
+	}
+	return data
+}
+
+// addVary adds a field name to the Vary header unless it is already listed.
+func addVary(header http.Header, field string) {
+	for _, value := range header.Values("Vary") {
+		for _, existing := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), field) {
+				return
+			}
+		}
+	}
+	header.Add("Vary", field)
+}
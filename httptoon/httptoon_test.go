@@ -0,0 +1,186 @@
+package httptoon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type product struct {
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+var products = []product{{ID: 1, Name: "Pen", Price: 1.5}, {ID: 2, Name: "Ink", Price: 4}}
+
+func request(method, body string, headers ...string) *http.Request {
+	r := httptest.NewRequest(method, "/products", strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	return r
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", JSONContentType},
+		{"application/toon", ContentType},
+		{"application/json", JSONContentType},
+		{"text/html", JSONContentType},
+		{"*/*", JSONContentType},
+		{"application/toon, */*;q=0.8", ContentType},
+		{"application/json;q=0.9, application/toon", ContentType},
+		{"application/json, application/toon;q=0.5", JSONContentType},
+		{"application/toon;q=0, */*", JSONContentType},
+		{"application/toon, application/json", ContentType},
+		{"application/json, application/toon", JSONContentType},
+		{"application/*, application/json;q=0.1", ContentType},
+	}
+
+	for _, test := range tests {
+		r := request(http.MethodGet, "", "Accept", test.accept)
+		if got := Negotiate(r); got != test.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", test.accept, got, test.want)
+		}
+	}
+}
+
+func TestRespond(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := Respond(w, request(http.MethodGet, "", "Accept", "application/toon"), products); err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/toon; charset=utf-8" {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("Expected Vary: Accept, got %q", vary)
+	}
+	expected := "items[2]{id,name,price}:\n  1,Pen,1.5\n  2,Ink,4\n"
+	if w.Body.String() != expected {
+		t.Errorf("Unexpected body.\nExpected:\n%s\nGot:\n%s", expected, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	if err := Respond(w, request(http.MethodGet, ""), products); err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != JSONContentType || !strings.HasPrefix(w.Body.String(), `[{"id":1,`) {
+		t.Errorf("Expected a JSON response, got %q: %s", ct, w.Body.String())
+	}
+}
+
+// money is written as "<cents> <currency>" in TOON and as an object in JSON.
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m money) MarshalTOON() (any, error) {
+	return fmt.Sprintf("%d %s", m.Cents, m.Currency), nil
+}
+
+func TestRespondUsesToonMarshaler(t *testing.T) {
+	type invoice struct {
+		Number string `toon:"no" json:"number"`
+		Total  money  `toon:"total" json:"total"`
+	}
+	data := invoice{Number: "A-1", Total: money{Cents: 950, Currency: "EUR"}}
+
+	w := httptest.NewRecorder()
+	if err := Respond(w, request(http.MethodGet, "", "Accept", "application/toon"), data); err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	if w.Body.String() != "no: A-1\ntotal: 950 EUR\n" {
+		t.Errorf("Unexpected TOON body:\n%s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	if err := Respond(w, request(http.MethodGet, ""), data); err != nil {
+		t.Fatalf("Respond failed: %v", err)
+	}
+	if w.Body.String() != `{"number":"A-1","total":{"Cents":950,"Currency":"EUR"}}`+"\n" {
+		t.Errorf("Unexpected JSON body:\n%s", w.Body.String())
+	}
+}
+
+func TestDecodeRequest(t *testing.T) {
+	var got []product
+	r := request(http.MethodPost, "items[2]{id,name,price}:\n  1,Pen,1.5\n  2,Ink,4", "Content-Type", "application/toon; charset=utf-8")
+	if err := DecodeRequest(r, &got); err != nil {
+		t.Fatalf("DecodeRequest failed: %v", err)
+	}
+	if len(got) != 2 || got[1] != products[1] {
+		t.Errorf("Unexpected products: %+v", got)
+	}
+
+	var single product
+	r = request(http.MethodPost, `{"id":3,"name":"Pad","price":2}`)
+	if err := DecodeRequest(r, &single); err != nil || single.Name != "Pad" {
+		t.Errorf("Expected a JSON body to decode, got %+v, %v", single, err)
+	}
+
+	r = request(http.MethodPost, "<p/>", "Content-Type", "text/html")
+	if err := DecodeRequest(r, &single); !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("Expected ErrUnsupportedMediaType, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodPost, "name: Pen\nprice: 1.5",
+		"Content-Type", "application/toon", "Accept", "application/toon"))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, ContentType) {
+		t.Errorf("Expected a TOON response, got %q", ct)
+	}
+	if w.Body.String() != "name: Pen\nprice: 1.5\n" {
+		t.Errorf("Unexpected body: %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodPost, "name: Pen", "Content-Type", "application/toon"))
+	if w.Body.String() != `{"name":"Pen"}` || w.Header().Get("Vary") != "Accept" {
+		t.Errorf("Expected the JSON body to pass through, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodPost, "<p/>", "Content-Type", "text/html", "Accept", "application/toon"))
+	if w.Body.String() != "<p/>" {
+		t.Errorf("Expected a non-JSON response to pass through, got %q", w.Body.String())
+	}
+}
+
+func TestMiddlewareKeepsLargeIntegers(t *testing.T) {
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1234567890123456789,"ref":9007199254740993,"price":1.5}`))
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request(http.MethodGet, "", "Accept", "application/toon"))
+
+	expected := "id: 1234567890123456789\nprice: 1.5\nref: 9007199254740993\n"
+	if w.Body.String() != expected {
+		t.Errorf("Unexpected body.\nExpected:\n%s\nGot:\n%s", expected, w.Body.String())
+	}
+}
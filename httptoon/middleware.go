@@ -0,0 +1,156 @@
+package httptoon
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/b92c/gotoon"
+)
+
+// Middleware lets JSON handlers speak TOON. Request bodies sent as
+// application/toon are decoded and handed to next as application/json, and
+// JSON responses, including "+json" media types, are rewritten as TOON when
+// the client prefers it. Other responses pass through unchanged. A request
+// body that isn't valid TOON is rejected with 400 Bad Request.
+func (c *Codec) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mediaType(r.Header.Get("Content-Type")) == ContentType {
+			if err := c.convertRequest(r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		addVary(w.Header(), "Accept")
+		if Negotiate(r) != ContentType {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		c.flush(w, rec)
+	})
+}
+
+// convertRequest replaces a TOON request body with its JSON encoding.
+func (c *Codec) convertRequest(r *http.Request) error {
+	src, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body.Close()
+
+	decoded, err := c.decoder.Decode(string(src))
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(unwrapItems(decoded))
+	if err != nil {
+		return err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", JSONContentType)
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// flush writes a recorded response, converting a JSON body to TOON.
+func (c *Codec) flush(w http.ResponseWriter, rec *responseRecorder) {
+	body := rec.body.Bytes()
+
+	if isJSONMediaType(mediaType(w.Header().Get("Content-Type"))) {
+		if toon, ok := jsonToTOON(c.encoder, body); ok {
+			body = toon
+			w.Header().Set("Content-Type", ContentType+"; charset=utf-8")
+			w.Header().Del("Content-Length")
+		}
+	}
+
+	w.WriteHeader(rec.status)
+	w.Write(body)
+}
+
+// jsonToTOON re-encodes a JSON body as TOON. It reports false when the body
+// isn't valid JSON. Numbers are decoded as int when they fit, so large IDs
+// keep every digit.
+func jsonToTOON(encoder *gotoon.Encoder, body []byte) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, false
+	}
+	toon, err := encoder.Encode(convertNumbers(value))
+	if err != nil {
+		return nil, false
+	}
+	return []byte(toon + "\n"), true
+}
+
+// convertNumbers replaces json.Number values with int or float64.
+func convertNumbers(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]any:
+		for k, item := range val {
+			val[k] = convertNumbers(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = convertNumbers(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// responseRecorder buffers a response so it can be converted before it is sent.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records the status code.
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+// Write buffers the body.
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	return r.body.Write(p)
+}
+
+// mediaType returns the media type of a Content-Type header without parameters.
+func mediaType(header string) string {
+	parsed, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+	return parsed
+}
+
+// isJSONMediaType reports whether a media type is application/json or uses the
+// "+json" structured syntax suffix, e.g. application/problem+json.
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == JSONContentType || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,127 @@
+package httptoon
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/b92c/gotoon"
+)
+
+// OriginalContentTypeHeader records the Content-Type of a response body that
+// Transport rewrote as TOON.
+const OriginalContentTypeHeader = "X-Original-Content-Type"
+
+// Transport is an http.RoundTripper that rewrites JSON response bodies as
+// TOON, so results of JSON APIs can be handed to a model as they are. Responses
+// with a JSON media type ("application/json" or a "+json" suffix) and an
+// unencoded body within the size limits are converted; everything else,
+// including bodies that fail to parse, is returned unchanged.
+//
+// A converted response has Content-Type "application/toon; charset=utf-8",
+// the original Content-Type in OriginalContentTypeHeader and an updated
+// ContentLength. Integers beyond float64 precision, such as 64-bit IDs, keep
+// every digit.
+type Transport struct {
+	// Base performs the requests. Nil means http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Encoder writes the TOON bodies. Nil means an encoder with
+	// gotoon.DefaultConfig.
+	Encoder *gotoon.Encoder
+
+	// MinSize is the smallest body, in bytes, worth converting. Smaller
+	// bodies are left as JSON.
+	MinSize int64
+
+	// MaxSize is the largest body, in bytes, that is buffered for conversion.
+	// Larger bodies stream through as JSON. Zero means no limit.
+	MaxSize int64
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || !t.convertible(resp) {
+		return resp, err
+	}
+
+	limit := t.MaxSize
+	if limit <= 0 {
+		limit = -1
+	}
+	body, complete, err := readUpTo(resp.Body, limit)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if !complete {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	encoder := t.Encoder
+	if encoder == nil {
+		encoder = defaultCodec.encoder
+	}
+
+	var toon []byte
+	ok := int64(len(body)) >= t.MinSize
+	if ok {
+		toon, ok = jsonToTOON(encoder, body)
+	}
+	if !ok {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+
+	resp.Header.Set(OriginalContentTypeHeader, resp.Header.Get("Content-Type"))
+	resp.Header.Set("Content-Type", ContentType+"; charset=utf-8")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(toon)))
+	resp.ContentLength = int64(len(toon))
+	resp.Body = io.NopCloser(bytes.NewReader(toon))
+	return resp, nil
+}
+
+// convertible reports whether resp has an unencoded JSON body whose declared
+// length is within the size limits.
+func (t *Transport) convertible(resp *http.Response) bool {
+	if resp.Body == nil || resp.Body == http.NoBody || resp.Header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if !isJSONMediaType(mediaType(resp.Header.Get("Content-Type"))) {
+		return false
+	}
+	if resp.ContentLength >= 0 {
+		if resp.ContentLength < t.MinSize || (t.MaxSize > 0 && resp.ContentLength > t.MaxSize) {
+			return false
+		}
+	}
+	return true
+}
+
+// readUpTo reads r to the end, or up to limit bytes when limit is not
+// negative. complete is false when r holds more than limit bytes, in which case
+// the bytes read so far are returned.
+func readUpTo(r io.Reader, limit int64) (data []byte, complete bool, err error) {
+	if limit < 0 {
+		data, err = io.ReadAll(r)
+		return data, err == nil, err
+	}
+
+	data, err = io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	return data, int64(len(data)) <= limit, nil
+}
@@ -0,0 +1,126 @@
+package httptoon
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/b92c/gotoon"
+)
+
+func jsonServer(t *testing.T, contentType, body string, chunked bool) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		io.WriteString(w, body)
+		if chunked {
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, transport *Transport, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := (&http.Client{Transport: transport}).Get(url)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Reading body failed: %v", err)
+	}
+	return resp, string(body)
+}
+
+const productsJSON = `[{"id":1,"name":"Pen","price":1.5},{"id":2,"name":"Ink","price":4}]`
+
+func TestTransport(t *testing.T) {
+	server := jsonServer(t, "application/json; charset=utf-8", productsJSON, false)
+
+	resp, body := get(t, &Transport{}, server.URL)
+	if body != "items[2]{id,name,price}:\n  1,Pen,1.5\n  2,Ink,4\n" {
+		t.Errorf("Unexpected body:\n%s", body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/toon; charset=utf-8" {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+	if original := resp.Header.Get(OriginalContentTypeHeader); original != "application/json; charset=utf-8" {
+		t.Errorf("Unexpected original content type %q", original)
+	}
+	if resp.ContentLength != int64(len(body)) {
+		t.Errorf("Expected ContentLength %d, got %d", len(body), resp.ContentLength)
+	}
+}
+
+func TestTransportKeepsLargeIntegers(t *testing.T) {
+	server := jsonServer(t, "application/json", `{"id":1234567890123456789,"ref":9007199254740993}`, false)
+
+	_, body := get(t, &Transport{}, server.URL)
+	if body != "id: 1234567890123456789\nref: 9007199254740993\n" {
+		t.Errorf("Unexpected body:\n%s", body)
+	}
+}
+
+func TestTransportUsesEncoder(t *testing.T) {
+	server := jsonServer(t, "application/vnd.api+json", `{"users":[{"id":1,"role":"admin"},{"id":2,"role":"admin"}]}`, false)
+
+	config := gotoon.DefaultConfig()
+	config.HoistConstantColumns = true
+
+	_, body := get(t, &Transport{Encoder: gotoon.NewEncoder(config)}, server.URL)
+	if !strings.Contains(body, "@{role=admin}") {
+		t.Errorf("Expected the configured encoder to be used, got:\n%s", body)
+	}
+}
+
+func TestTransportSizeLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport *Transport
+		chunked   bool
+		converted bool
+	}{
+		{"below MinSize", &Transport{MinSize: 1000}, false, false},
+		{"below MinSize without length", &Transport{MinSize: 1000}, true, false},
+		{"above MaxSize", &Transport{MaxSize: 10}, false, false},
+		{"above MaxSize without length", &Transport{MaxSize: 10}, true, false},
+		{"within limits", &Transport{MinSize: 10, MaxSize: 1000}, true, true},
+	}
+
+	server := jsonServer(t, "application/json", productsJSON, false)
+	chunked := jsonServer(t, "application/json", productsJSON, true)
+
+	for _, test := range tests {
+		url := server.URL
+		if test.chunked {
+			url = chunked.URL
+		}
+
+		resp, body := get(t, test.transport, url)
+		converted := resp.Header.Get(OriginalContentTypeHeader) != ""
+		if converted != test.converted {
+			t.Errorf("%s: expected converted=%v, got body:\n%s", test.name, test.converted, body)
+		}
+		if !converted && body != productsJSON {
+			t.Errorf("%s: expected the JSON body unchanged, got:\n%s", test.name, body)
+		}
+	}
+}
+
+func TestTransportSkipsOtherResponses(t *testing.T) {
+	for _, server := range []*httptest.Server{
+		jsonServer(t, "text/plain", productsJSON, false),
+		jsonServer(t, "application/json", `{"broken":`, false),
+	} {
+		resp, body := get(t, &Transport{}, server.URL)
+		if resp.Header.Get(OriginalContentTypeHeader) != "" || strings.HasPrefix(body, "items") {
+			t.Errorf("Expected the response to pass through, got:\n%s", body)
+		}
+	}
+}
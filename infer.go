@@ -0,0 +1,427 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// JSON Schema type names used by InferredSchema.
+const (
+	schemaObject  = "object"
+	schemaArray   = "array"
+	schemaString  = "string"
+	schemaNumber  = "number"
+	schemaInteger = "integer"
+	schemaBoolean = "boolean"
+	schemaNull    = "null"
+)
+
+// maxEnumValues is the largest number of distinct strings inferred as an enum.
+const maxEnumValues = 8
+
+// InferredSchema describes the shape of sample data. It marshals to JSON
+// Schema and can be rendered as Go struct definitions.
+type InferredSchema struct {
+	// Types lists the JSON Schema types seen, e.g. ["string", "null"].
+	Types []string
+
+	// Properties holds the schemas of object keys.
+	Properties map[string]*InferredSchema
+
+	// Required lists the object keys present in every sample.
+	Required []string
+
+	// Items is the schema of array items.
+	Items *InferredSchema
+
+	// Enum lists the values of a low-cardinality string field.
+	Enum []string
+
+	// Format is "date-time" for strings that are all RFC 3339 timestamps.
+	Format string
+}
+
+// InferSchema infers a schema from one or more sample documents. Arrays of
+// objects are walked with the ArrayFlattener, so nested objects become nested
+// properties up to MaxFlattenDepth of the default config, and keys missing
+// from some items are not required. Fields that hold nil in some samples are
+// nullable, and strings that repeat a few distinct values become enums.
+func InferSchema(samples ...any) *InferredSchema {
+	in := &schemaInferrer{flattener: NewArrayFlattener(DefaultConfig().MaxFlattenDepth)}
+	return in.infer(samples)
+}
+
+// schemaInferrer merges the values seen at one location into a schema.
+type schemaInferrer struct {
+	flattener *ArrayFlattener
+}
+
+// infer returns the schema of the values seen at one location.
+func (in *schemaInferrer) infer(values []any) *InferredSchema {
+	s := &InferredSchema{}
+	types := make(map[string]bool)
+
+	var objects, elements []any
+	var strs []string
+	allTimes := true
+	for _, value := range values {
+		switch val := value.(type) {
+		case nil:
+			types[schemaNull] = true
+		case map[string]any:
+			types[schemaObject] = true
+			objects = append(objects, val)
+		case []any:
+			types[schemaArray] = true
+			elements = append(elements, val...)
+		case time.Time:
+			types[schemaString] = true
+		case string:
+			types[schemaString] = true
+			strs = append(strs, val)
+			if _, err := time.Parse(time.RFC3339, val); err != nil {
+				allTimes = false
+			}
+		default:
+			switch valueType(val) {
+			case intColumnType:
+				types[schemaInteger] = true
+			case floatColumnType:
+				types[schemaNumber] = true
+			case boolColumnType:
+				types[schemaBoolean] = true
+			}
+		}
+	}
+
+	if types[schemaNumber] {
+		delete(types, schemaInteger)
+	}
+	for _, typ := range []string{schemaObject, schemaArray, schemaString, schemaNumber, schemaInteger, schemaBoolean, schemaNull} {
+		if types[typ] {
+			s.Types = append(s.Types, typ)
+		}
+	}
+
+	if len(objects) > 0 {
+		in.inferObjects(s, objects)
+	}
+	if types[schemaArray] {
+		s.Items = in.infer(elements)
+	}
+	if types[schemaString] {
+		switch {
+		case allTimes:
+			s.Format = "date-time"
+		case s.onlyType(schemaString):
+			s.Enum = inferEnum(strs)
+		}
+	}
+
+	return s
+}
+
+// inferObjects fills in the properties of s from the objects seen at its location.
+func (in *schemaInferrer) inferObjects(s *InferredSchema, objects []any) {
+	flattened := in.flattener.Flatten(objects)
+
+	for j, column := range flattened.Columns {
+		var values []any
+		required := true
+		for i, row := range flattened.Rows {
+			if flattened.Missing != nil && flattened.Missing[i][j] {
+				required = false
+				continue
+			}
+			values = append(values, row[j])
+		}
+
+		parent := s
+		segments := strings.Split(column, ".")
+		for k, segment := range segments[:len(segments)-1] {
+			prefix := strings.Join(segments[:k+1], ".")
+			parent = parent.property(segment, in.presentInAll(objects, prefix))
+		}
+
+		key := segments[len(segments)-1]
+		leaf := in.infer(values)
+		if existing, ok := parent.Properties[key]; ok {
+			// The key also holds objects in other items.
+			for _, typ := range leaf.Types {
+				if !existing.hasType(typ) {
+					existing.Types = append(existing.Types, typ)
+				}
+			}
+			continue
+		}
+		parent.setProperty(key, leaf, required)
+	}
+}
+
+// presentInAll reports whether every object has the dotted path.
+func (in *schemaInferrer) presentInAll(objects []any, path string) bool {
+	for _, obj := range objects {
+		if _, ok := in.flattener.lookupPath(obj, path); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// property returns the object property key, creating it when needed.
+func (s *InferredSchema) property(key string, required bool) *InferredSchema {
+	if child, ok := s.Properties[key]; ok {
+		if !child.hasType(schemaObject) {
+			child.Types = append([]string{schemaObject}, child.Types...)
+		}
+		return child
+	}
+
+	child := &InferredSchema{Types: []string{schemaObject}}
+	s.setProperty(key, child, required)
+	return child
+}
+
+// setProperty adds a property, keeping Required sorted.
+func (s *InferredSchema) setProperty(key string, child *InferredSchema, required bool) {
+	if s.Properties == nil {
+		s.Properties = make(map[string]*InferredSchema)
+	}
+	s.Properties[key] = child
+
+	if required {
+		s.Required = append(s.Required, key)
+		sort.Strings(s.Required)
+	}
+}
+
+// hasType reports whether typ is one of the schema's types.
+func (s *InferredSchema) hasType(typ string) bool {
+	for _, t := range s.Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyType reports whether typ is the only type besides null.
+func (s *InferredSchema) onlyType(typ string) bool {
+	for _, t := range s.Types {
+		if t != typ && t != schemaNull {
+			return false
+		}
+	}
+	return s.hasType(typ)
+}
+
+// inferEnum returns the distinct values of strs when there are few of them and
+// each repeats on average, or nil.
+func inferEnum(strs []string) []string {
+	seen := make(map[string]bool)
+	for _, str := range strs {
+		seen[str] = true
+	}
+
+	if len(seen) > maxEnumValues || len(strs) < 2*len(seen) {
+		return nil
+	}
+
+	enum := make([]string, 0, len(seen))
+	for str := range seen {
+		enum = append(enum, str)
+	}
+	sort.Strings(enum)
+	return enum
+}
+
+// MarshalJSON writes the schema as JSON Schema.
+func (s *InferredSchema) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any)
+
+	switch len(s.Types) {
+	case 0:
+	case 1:
+		out["type"] = s.Types[0]
+	default:
+		out["type"] = s.Types
+	}
+
+	if len(s.Properties) > 0 {
+		out["properties"] = s.Properties
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	if s.Items != nil {
+		out["items"] = s.Items
+	}
+	if len(s.Enum) > 0 {
+		enum := make([]any, 0, len(s.Enum)+1)
+		for _, value := range s.Enum {
+			enum = append(enum, value)
+		}
+		if s.hasType(schemaNull) {
+			enum = append(enum, nil)
+		}
+		out["enum"] = enum
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+
+	return json.Marshal(out)
+}
+
+// JSONSchema returns the schema as an indented JSON Schema document.
+func (s *InferredSchema) JSONSchema() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// GoStruct renders the schema as gofmt'd Go type definitions, starting with a
+// type called name. Nested objects get their own types named after their path,
+// e.g. OrderCustomer. For an array the types describe its items. Timestamps use
+// time.Time, so the caller's file must import "time". Fields get a struct tag
+// per name in tags, e.g. `toon:"id" json:"id"`; the default is "json".
+func (s *InferredSchema) GoStruct(name string, tags ...string) string {
+	if len(tags) == 0 {
+		tags = []string{"json"}
+	}
+	g := &structGenerator{names: make(map[string]bool), tags: tags}
+
+	root := s
+	for root.Items != nil && root.onlyType(schemaArray) {
+		root = root.Items
+	}
+
+	if root.onlyType(schemaObject) {
+		g.object(goName(name), root)
+	} else {
+		g.decls = append(g.decls, fmt.Sprintf("type %s %s\n", goName(name), g.goType(goName(name), root)))
+	}
+
+	src := strings.Join(g.decls, "\n")
+	if formatted, err := format.Source([]byte(src)); err == nil {
+		return string(formatted)
+	}
+	return src
+}
+
+// structGenerator collects the type declarations of GoStruct.
+type structGenerator struct {
+	decls []string
+	names map[string]bool
+	tags  []string
+}
+
+// object declares a struct type for an object schema and its nested objects.
+func (g *structGenerator) object(name string, s *InferredSchema) {
+	g.names[name] = true
+	index := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	required := make(map[string]bool, len(s.Required))
+	for _, key := range s.Required {
+		required[key] = true
+	}
+
+	keys := make([]string, 0, len(s.Properties))
+	for key := range s.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	fields := make(map[string]bool)
+	for _, key := range keys {
+		field := goName(key)
+		for fields[field] {
+			field += "_"
+		}
+		fields[field] = true
+
+		value := key
+		if !required[key] {
+			value += ",omitempty"
+		}
+		tags := make([]string, len(g.tags))
+		for i, tag := range g.tags {
+			tags[i] = fmt.Sprintf("%s:%q", tag, value)
+		}
+		fmt.Fprintf(&b, "\t%s %s `%s`\n", field, g.goType(name+field, s.Properties[key]), strings.Join(tags, " "))
+	}
+	b.WriteString("}\n")
+
+	g.decls[index] = b.String()
+}
+
+// goType returns the Go type for a schema, declaring nested struct types named
+// after name. Nullable scalars and objects become pointers.
+func (g *structGenerator) goType(name string, s *InferredSchema) string {
+	nullable := s.hasType(schemaNull)
+
+	var typ string
+	switch {
+	case s.onlyType(schemaObject):
+		for g.names[name] {
+			name += "_"
+		}
+		g.object(name, s)
+		typ = name
+	case s.onlyType(schemaArray):
+		return "[]" + g.goType(name+"Item", s.Items)
+	case s.onlyType(schemaString) && s.Format == "date-time":
+		typ = "time.Time"
+	case s.onlyType(schemaString):
+		typ = "string"
+	case s.onlyType(schemaInteger):
+		typ = "int"
+	case s.onlyType(schemaNumber):
+		typ = "float64"
+	case s.onlyType(schemaBoolean):
+		typ = "bool"
+	default:
+		return "any"
+	}
+
+	if nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+// commonInitialisms are written in upper case in Go names.
+var commonInitialisms = map[string]bool{
+	"api": true, "http": true, "id": true, "ip": true, "json": true,
+	"sku": true, "sql": true, "url": true, "uuid": true,
+}
+
+// goName converts a key such as "customer_id" to an exported Go name, "CustomerID".
+func goName(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		if commonInitialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
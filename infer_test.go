@@ -0,0 +1,133 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sampleOrders() []any {
+	return []any{
+		map[string]any{
+			"id": 1, "status": "open", "total": 9.5, "created_at": "2024-01-02T10:00:00Z",
+			"customer": map[string]any{"id": "c1", "name": "Alice"},
+			"tags":     []any{"a", "b"},
+			"note":     nil,
+		},
+		map[string]any{
+			"id": 2, "status": "open", "total": 12, "created_at": "2024-01-03T11:30:00Z",
+			"customer": map[string]any{"id": "c2", "name": "Bob", "vip": true},
+			"tags":     []any{},
+			"note":     "leave at door",
+		},
+		map[string]any{
+			"id": 3, "status": "closed", "total": 3, "created_at": "2024-01-04T08:15:00Z",
+			"customer": map[string]any{"id": "c3", "name": "Carol"},
+			"tags":     []any{"c"},
+		},
+		map[string]any{
+			"id": 4, "status": "open", "total": 1, "created_at": "2024-01-05T09:45:00Z",
+			"customer": map[string]any{"id": "c4", "name": "Dave"},
+			"tags":     []any{},
+		},
+	}
+}
+
+func TestInferSchema(t *testing.T) {
+	s := InferSchema(sampleOrders()...)
+
+	if !reflect.DeepEqual(s.Types, []string{"object"}) {
+		t.Fatalf("Expected object schema, got %v", s.Types)
+	}
+
+	expectedRequired := []string{"created_at", "customer", "id", "status", "tags", "total"}
+	if !reflect.DeepEqual(s.Required, expectedRequired) {
+		t.Errorf("Expected required %v, got %v", expectedRequired, s.Required)
+	}
+
+	checks := map[string][]string{
+		"id":    {"integer"},
+		"total": {"number"},
+		"note":  {"string", "null"},
+		"tags":  {"array"},
+	}
+	for key, types := range checks {
+		if got := s.Properties[key].Types; !reflect.DeepEqual(got, types) {
+			t.Errorf("%s: expected types %v, got %v", key, types, got)
+		}
+	}
+
+	if enum := s.Properties["status"].Enum; !reflect.DeepEqual(enum, []string{"closed", "open"}) {
+		t.Errorf("Expected status enum, got %v", enum)
+	}
+	if s.Properties["customer"].Properties["name"].Enum != nil {
+		t.Errorf("Expected unique names not to become an enum")
+	}
+	if format := s.Properties["created_at"].Format; format != "date-time" {
+		t.Errorf("Expected date-time format, got %q", format)
+	}
+	if items := s.Properties["tags"].Items; !reflect.DeepEqual(items.Types, []string{"string"}) {
+		t.Errorf("Expected string items, got %+v", items)
+	}
+
+	customer := s.Properties["customer"]
+	if !reflect.DeepEqual(customer.Required, []string{"id", "name"}) {
+		t.Errorf("Expected vip to be optional, got required %v", customer.Required)
+	}
+	if !reflect.DeepEqual(customer.Properties["vip"].Types, []string{"boolean"}) {
+		t.Errorf("Expected nested boolean property, got %+v", customer.Properties["vip"])
+	}
+}
+
+func TestInferSchemaJSON(t *testing.T) {
+	data, err := InferSchema(map[string]any{"orders": sampleOrders()}).JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+
+	items := doc["properties"].(map[string]any)["orders"].(map[string]any)["items"].(map[string]any)
+	note := items["properties"].(map[string]any)["note"].(map[string]any)
+	if !reflect.DeepEqual(note["type"], []any{"string", "null"}) {
+		t.Errorf("Expected nullable type list, got %v", note["type"])
+	}
+
+	status := items["properties"].(map[string]any)["status"].(map[string]any)
+	if status["type"] != "string" || !reflect.DeepEqual(status["enum"], []any{"closed", "open"}) {
+		t.Errorf("Unexpected status schema: %v", status)
+	}
+}
+
+func TestInferSchemaGoStruct(t *testing.T) {
+	src := InferSchema(sampleOrders()).GoStruct("order")
+
+	for _, want := range []string{
+		"type Order struct {",
+		"CreatedAt time.Time `json:\"created_at\"`",
+		"Customer  OrderCustomer `json:\"customer\"`",
+		"ID        int `json:\"id\"`",
+		"Note      *string `json:\"note,omitempty\"`",
+		"Tags      []string `json:\"tags\"`",
+		"Total     float64 `json:\"total\"`",
+		"type OrderCustomer struct {",
+		"Vip  bool `json:\"vip,omitempty\"`",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(src), " "), strings.Join(strings.Fields(want), " ")) {
+			t.Errorf("Expected %q in generated code:\n%s", want, src)
+		}
+	}
+}
+
+func TestInferSchemaGoStructTags(t *testing.T) {
+	src := InferSchema(sampleOrders()).GoStruct("order", "toon", "json")
+
+	want := "Note *string `toon:\"note,omitempty\" json:\"note,omitempty\"`"
+	if !strings.Contains(strings.Join(strings.Fields(src), " "), want) {
+		t.Errorf("Expected %q in generated code:\n%s", want, src)
+	}
+}
@@ -0,0 +1,109 @@
+package gotoon
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// jsonColumnType marks table columns whose cells hold JSON-encoded arrays or
+// objects, e.g. "tags:json".
+const jsonColumnType = "json"
+
+// isJSONColumn checks if any cell in the column holds an array or object that
+// will be written as JSON.
+func isJSONColumn(rows [][]any, col int) bool {
+	for _, row := range rows {
+		if col >= len(row) {
+			continue
+		}
+		switch row[col].(type) {
+		case []any, map[string]any:
+			return true
+		}
+	}
+	return false
+}
+
+// jsonCell writes a cell of a JSON column. Every value is JSON-encoded, so
+// strings and numbers in the column decode back to themselves.
+func (e *Encoder) jsonCell(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return e.escapeScalar(value)
+	}
+	return e.escapeText(string(data))
+}
+
+// decodeJSONCell restores a JSON-encoded cell. Cells that are not valid JSON are
+// returned as plain strings along with the parse error; empty cells decode to nil.
+func decodeJSONCell(raw string) (any, error) {
+	text := unescapeCell(strings.TrimSpace(raw))
+	if text == "" {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(text)))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return text, err
+	}
+
+	return normalizeJSON(value), nil
+}
+
+// normalizeJSON converts json.Number values to int or float64, matching the
+// types produced by parseValue.
+func normalizeJSON(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return int(i)
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	case map[string]any:
+		for k, item := range val {
+			val[k] = normalizeJSON(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeJSON(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// unescapeCell resolves backslash escapes in a single pass.
+func unescapeCell(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped && c == 'n':
+			b.WriteByte('\n')
+			escaped = false
+		case escaped:
+			b.WriteByte(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
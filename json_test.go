@@ -0,0 +1,159 @@
+package gotoon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONColumnsRoundTrip(t *testing.T) {
+	data := []any{
+		map[string]any{"id": 1, "tags": []any{"a", "b, c"}, "name": "Alice"},
+		map[string]any{"id": 2, "tags": []any{}, "name": "Bob"},
+	}
+
+	toon, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.HasPrefix(toon, "items[2]{id,name,tags:json}:") {
+		t.Errorf("Expected json marker on tags column, got: %s", toon)
+	}
+	if !strings.Contains(toon, `["a"\,"b\, c"]`) {
+		t.Errorf("Expected escaped JSON cell, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	first := items[0].(map[string]any)
+	tags, ok := first["tags"].([]any)
+	if !ok || len(tags) != 2 || tags[1] != "b, c" {
+		t.Errorf("Expected tags to be restored as a list, got: %#v", first["tags"])
+	}
+	if first["name"] != "Alice" {
+		t.Errorf("Expected name='Alice', got: %v", first["name"])
+	}
+
+	second := items[1].(map[string]any)
+	if tags, ok := second["tags"].([]any); !ok || len(tags) != 0 {
+		t.Errorf("Expected empty tags list, got: %#v", second["tags"])
+	}
+}
+
+func TestJSONColumnWithMixedCellsRoundTrip(t *testing.T) {
+	data := []any{
+		map[string]any{"a": "[2]", "id": 1},
+		map[string]any{"a": "x", "id": 2},
+		map[string]any{"a": 3, "id": 3},
+		map[string]any{"a": []any{"y", 4}, "id": 4},
+		map[string]any{"a": nil, "id": 5},
+	}
+
+	toon, err := Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "items[5]{a:json,id}:\n  \"[2]\",1\n  \"x\",2\n  3,3\n  [\"y\"\\,4],4\n  ,5"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	config := DefaultConfig()
+	config.Strict = true
+	decoded, err := NewDecoder(config).Decode(toon)
+	if err != nil {
+		t.Fatalf("Strict decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded["items"], data) {
+		t.Errorf("Round trip mismatch.\nExpected: %v\nGot:      %v", data, decoded["items"])
+	}
+}
+
+func TestObjectsDeeperThanMaxFlattenDepthRoundTrip(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxFlattenDepth = 1
+
+	data := []any{
+		map[string]any{
+			"id": 1,
+			"event": map[string]any{
+				"name":  "Festival",
+				"venue": map[string]any{"name": "Club X", "capacity": 500, "rating": 4.5, "note": "line\nbreak"},
+			},
+		},
+		map[string]any{
+			"id": 2,
+			"event": map[string]any{
+				"name":  "Concert",
+				"venue": map[string]any{"name": "Arena: Y", "capacity": 12000, "rating": 3.9, "note": nil},
+			},
+		},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.Contains(toon, "event.venue:json") {
+		t.Errorf("Expected json marker on deep column, got: %s", toon)
+	}
+
+	decoded, err := NewDecoder(config).Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	first := items[0].(map[string]any)["event"].(map[string]any)["venue"].(map[string]any)
+	if first["capacity"] != 500 {
+		t.Errorf("Expected integer capacity 500, got: %#v", first["capacity"])
+	}
+	if first["rating"] != 4.5 {
+		t.Errorf("Expected float rating 4.5, got: %#v", first["rating"])
+	}
+	if first["note"] != "line\nbreak" {
+		t.Errorf("Expected newline to survive, got: %#v", first["note"])
+	}
+
+	second := items[1].(map[string]any)["event"].(map[string]any)["venue"].(map[string]any)
+	if second["name"] != "Arena: Y" {
+		t.Errorf("Expected name='Arena: Y', got: %#v", second["name"])
+	}
+}
+
+func TestJSONColumnHoistedAsAttribute(t *testing.T) {
+	config := DefaultConfig()
+	config.HoistConstantColumns = true
+
+	data := []any{
+		map[string]any{"id": 1, "roles": []any{"admin", "dev"}},
+		map[string]any{"id": 2, "roles": []any{"admin", "dev"}},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.Contains(toon, `@{roles:json=["admin"\,"dev"]}:`) {
+		t.Errorf("Expected hoisted JSON attribute, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	roles, ok := items[1].(map[string]any)["roles"].([]any)
+	if !ok || len(roles) != 2 || roles[0] != "admin" {
+		t.Errorf("Expected roles to be restored, got: %#v", items[1])
+	}
+}
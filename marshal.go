@@ -0,0 +1,320 @@
+package gotoon
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToonMarshaler is implemented by types that encode themselves as a TOON
+// value. MarshalTOON returns the value to encode in their place: a scalar,
+// map[string]any, []any, or any other value the encoder accepts.
+type ToonMarshaler interface {
+	MarshalTOON() (any, error)
+}
+
+// ToonUnmarshaler is implemented by types that decode themselves from the
+// value the decoder produced for them: nil, a string, bool, int, float64,
+// time.Time, map[string]any or []any.
+type ToonUnmarshaler interface {
+	UnmarshalTOON(value any) error
+}
+
+// marshalValue converts v to the maps, slices and scalars the encoder writes.
+// A transformer configured for the type of v runs first. ToonMarshaler takes
+// precedence, then encoding.TextMarshaler, which becomes a string, then
+// json.Marshaler. Structs become maps keyed by their toon or json tags, other
+// maps and slices become map[string]any and []any, and []byte becomes base64
+// as in encoding/json. Values that need no conversion are returned as they
+// are.
+func (e *Encoder) marshalValue(v any, path string) (any, error) {
+	if transform, ok := e.config.TypeTransformers[reflect.TypeOf(v)]; ok {
+		v = transform(v)
+	}
+
+	switch val := v.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64, time.Time:
+		return v, nil
+
+	case map[string]any:
+		var out map[string]any
+		for _, key := range sortedKeys(val) {
+			item, err := e.marshalValue(val[key], joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			if out == nil && !sameValue(item, val[key]) {
+				out = make(map[string]any, len(val))
+				for k, existing := range val {
+					out[k] = existing
+				}
+			}
+			if out != nil {
+				out[key] = item
+			}
+		}
+		if out == nil {
+			return val, nil
+		}
+		return out, nil
+
+	case []any:
+		var out []any
+		for i, item := range val {
+			converted, err := e.marshalValue(item, joinPath(path, fmt.Sprint(i)))
+			if err != nil {
+				return nil, err
+			}
+			if out == nil && !sameValue(converted, item) {
+				out = append(make([]any, 0, len(val)), val[:i]...)
+			}
+			if out != nil {
+				out = append(out, converted)
+			}
+		}
+		if out == nil {
+			return val, nil
+		}
+		return out, nil
+
+	case ToonMarshaler:
+		if isNilPointer(v) {
+			return nil, nil
+		}
+		out, err := val.MarshalTOON()
+		if err != nil {
+			return nil, marshalError(path, v, err)
+		}
+		if _, again := out.(ToonMarshaler); again {
+			return nil, marshalError(path, v, fmt.Errorf("MarshalTOON returned another ToonMarshaler"))
+		}
+		return e.marshalValue(out, path)
+
+	case encoding.TextMarshaler:
+		if isNilPointer(v) {
+			return nil, nil
+		}
+		text, err := val.MarshalText()
+		if err != nil {
+			return nil, marshalError(path, v, err)
+		}
+		return string(text), nil
+
+	case json.Marshaler:
+		if isNilPointer(v) {
+			return nil, nil
+		}
+		data, err := val.MarshalJSON()
+		if err != nil {
+			return nil, marshalError(path, v, err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var out any
+		if err := decoder.Decode(&out); err != nil {
+			return nil, marshalError(path, v, err)
+		}
+		return normalizeJSON(out), nil
+	}
+
+	return e.marshalReflect(reflect.ValueOf(v), path)
+}
+
+// marshalReflect converts values of named, struct, map, slice and pointer
+// types.
+func (e *Encoder) marshalReflect(rv reflect.Value, path string) (any, error) {
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return e.marshalValue(rv.Elem().Interface(), path)
+
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+
+	case reflect.Struct:
+		obj := make(map[string]any)
+		for _, field := range cachedStructFields(rv.Type()) {
+			fv, ok := fieldByIndex(rv, field.index)
+			if !ok || (field.omitEmpty && fv.IsZero()) {
+				continue
+			}
+			value, err := e.marshalValue(fv.Interface(), joinPath(path, field.name))
+			if err != nil {
+				return nil, err
+			}
+			obj[field.name] = value
+		}
+		return obj, nil
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s: unsupported map key type %s", pathOrRoot(path), rv.Type().Key())
+		}
+		obj := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			value, err := e.marshalValue(iter.Value().Interface(), joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = value
+		}
+		return obj, nil
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 && rv.Kind() == reflect.Slice {
+			return base64.StdEncoding.EncodeToString(rv.Bytes()), nil
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			value, err := e.marshalValue(rv.Index(i).Interface(), joinPath(path, fmt.Sprint(i)))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+	}
+
+	return nil, fmt.Errorf("%s: unsupported type %s", pathOrRoot(path), rv.Type())
+}
+
+// marshalError wraps an error returned by a marshaler.
+func marshalError(path string, v any, err error) error {
+	return fmt.Errorf("%s: marshaling %T: %w", pathOrRoot(path), v, err)
+}
+
+// pathOrRoot names the value at path in errors.
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
+
+// sameValue reports whether a converted value is the original one, so
+// containers are only copied when something in them changed.
+func sameValue(a, b any) bool {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		return ok && reflect.ValueOf(av).UnsafePointer() == reflect.ValueOf(bv).UnsafePointer()
+	case []any:
+		bv, ok := b.([]any)
+		return ok && len(av) == len(bv) && (len(av) == 0 || &av[0] == &bv[0])
+	}
+
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if !reflect.TypeOf(a).Comparable() || reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	return a == b
+}
+
+// isNilPointer reports whether v is a nil pointer.
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// structField is a struct field as encoded: its key, index path and whether
+// zero values are left out.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+var structFieldCache sync.Map
+
+// cachedStructFields returns the encoded fields of struct type t.
+func cachedStructFields(t reflect.Type) []structField {
+	if fields, ok := structFieldCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields, _ := structFieldCache.LoadOrStore(t, structFields(t, nil))
+	return fields.([]structField)
+}
+
+// structFields lists the exported fields of t, named by their toon tag, then
+// their json tag, then the field name. Untagged embedded structs are inlined,
+// and "-" skips a field.
+func structFields(t reflect.Type, index []int) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("toon")
+		if !ok {
+			tag = field.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldIndex := append(index[:len(index):len(index)], i)
+
+		typ := field.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if field.Anonymous && name == "" && typ.Kind() == reflect.Struct {
+			fields = append(fields, structFields(typ, fieldIndex)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+		fields = append(fields, structField{name: name, index: fieldIndex, omitEmpty: omitEmpty})
+	}
+	return fields
+}
+
+// fieldByIndex returns the field at index, reporting false when it is inside
+// a nil embedded pointer.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
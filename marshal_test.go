@@ -0,0 +1,235 @@
+package gotoon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// money encodes itself as "<cents> <currency>" through ToonMarshaler.
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m money) MarshalTOON() (any, error) {
+	if m.Currency == "" {
+		return nil, errors.New("missing currency")
+	}
+	return fmt.Sprintf("%d %s", m.Cents, m.Currency), nil
+}
+
+func (m *money) UnmarshalTOON(value any) error {
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected a string, got %T", value)
+	}
+	_, err := fmt.Sscanf(s, "%d %s", &m.Cents, &m.Currency)
+	return err
+}
+
+// priority is an enum encoded through encoding.TextMarshaler.
+type priority int
+
+func (p priority) MarshalText() ([]byte, error) {
+	return []byte([]string{"low", "high"}[p]), nil
+}
+
+func (p *priority) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*p = 0
+	case "high":
+		*p = 1
+	default:
+		return fmt.Errorf("unknown priority %q", text)
+	}
+	return nil
+}
+
+// point is encoded through json.Marshaler.
+type point struct{ X, Y int }
+
+func (p point) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int{"lat": p.X, "lng": p.Y})
+}
+
+func (p *point) UnmarshalJSON(data []byte) error {
+	var raw map[string]int
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	p.X, p.Y = raw["lat"], raw["lng"]
+	return nil
+}
+
+type audit struct {
+	By string `json:"by"`
+}
+
+type order struct {
+	ID       int        `toon:"id"`
+	Total    money      `json:"total"`
+	Priority priority   `json:"priority"`
+	Where    point      `json:"where"`
+	Addr     netip.Addr `json:"addr"`
+	Note     string     `json:"note,omitempty"`
+	Secret   string     `json:"-"`
+	*audit
+}
+
+func TestEncodeMarshalers(t *testing.T) {
+	orders := []order{
+		{ID: 1, Total: money{1250, "USD"}, Priority: 1, Where: point{1, 2}, Addr: netip.MustParseAddr("10.0.0.1"), Secret: "x", audit: &audit{By: "ana"}},
+		{ID: 2, Total: money{99, "EUR"}, Where: point{3, 4}, Addr: netip.MustParseAddr("::1"), Note: "gift"},
+	}
+
+	toon, err := Encode(orders)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "items[2]{addr,by,id,priority,total,where.lat,where.lng,note}:\n" +
+		"  10.0.0.1,ana,1,high,1250 USD,1,2,\n" +
+		"  \\:\\:1,,2,low,99 EUR,3,4,gift"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	var decoded []order
+	if err := Unmarshal(toon, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	orders[0].Secret, orders[0].audit = "", nil
+	if !reflect.DeepEqual(decoded, orders) {
+		t.Errorf("Round trip mismatch.\nExpected: %+v\nGot:      %+v", orders, decoded)
+	}
+}
+
+func TestEncodeMarshalerScalars(t *testing.T) {
+	toon, err := Encode(map[string]any{
+		"price":   money{500, "USD"},
+		"missing": (*money)(nil),
+		"levels":  []priority{0, 1},
+		"raw":     []byte("hi"),
+	})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "levels:\n  - low\n  - high\nmissing: \nprice: 500 USD\nraw: aGk="
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+}
+
+func TestEncodeMarshalerError(t *testing.T) {
+	_, err := Encode(map[string]any{"items": []order{{ID: 1, Total: money{Cents: 5}}}})
+	if err == nil || !strings.Contains(err.Error(), "items.0.total: marshaling gotoon.money: missing currency") {
+		t.Errorf("Expected a marshaling error with its path, got %v", err)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type config struct {
+		Name    string            `toon:"name"`
+		Port    uint16            `json:"port"`
+		Ratio   float32           `json:"ratio"`
+		Debug   bool              `json:"debug"`
+		Version string            `json:"version"`
+		Started time.Time         `json:"started"`
+		Price   *money            `json:"price"`
+		Labels  map[string]string `json:"labels"`
+		Tags    [2]string         `json:"tags"`
+		Extra   any               `json:"extra"`
+	}
+
+	toon := "NAME: api\nport: 8080\nratio: 0.5\ndebug: true\nversion: 2\nstarted: 2024-01-02T10:00:00Z\n" +
+		"price: 300 USD\nlabels:\n  env: prod\ntags:\n  - a\n  - b\nextra:\n  n: 1\nunknown: ignored"
+
+	var got config
+	if err := Unmarshal(toon, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	expected := config{
+		Name:    "api",
+		Port:    8080,
+		Ratio:   0.5,
+		Debug:   true,
+		Version: "2",
+		Started: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+		Price:   &money{300, "USD"},
+		Labels:  map[string]string{"env": "prod"},
+		Tags:    [2]string{"a", "b"},
+		Extra:   map[string]any{"n": 1},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected value.\nExpected: %+v\nGot:      %+v", expected, got)
+	}
+}
+
+func TestUnmarshalKeepsStringText(t *testing.T) {
+	type addr struct {
+		Zip    string            `json:"zip"`
+		Code   string            `json:"code"`
+		Flag   string            `json:"flag"`
+		Labels map[string]string `json:"labels"`
+	}
+
+	list := []addr{{Zip: "02134", Code: "1.50", Flag: "true"}, {Zip: "90210", Code: "7", Flag: "no"}}
+	toon, err := Encode(list)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	var gotList []addr
+	if err := Unmarshal(toon, &gotList); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(gotList, list) {
+		t.Errorf("Round trip changed the rows.\nExpected: %+v\nGot:      %+v", list, gotList)
+	}
+
+	one := addr{Zip: "02134", Code: "1.50", Flag: "false", Labels: map[string]string{"floor": "03"}}
+	toon, err = Encode(one)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	var got addr
+	if err := Unmarshal(toon, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, one) {
+		t.Errorf("Round trip changed the object.\nExpected: %+v\nGot:      %+v", one, got)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		toon   string
+		target any
+		want   string
+	}{
+		{"not a pointer", "a: 1", order{}, "needs a non-nil pointer"},
+		{"wrong type", "id: abc", &order{}, "id: cannot store string in int"},
+		{"number for string", "zip: 02134", &struct{ Zip string }{}, "zip: cannot store int in string"},
+		{"overflow", "items[1]{n}:\n  300", &[]struct{ N uint8 }{}, "0.n: cannot store int in uint8"},
+		{"text unmarshaler", "priority: urgent", &order{}, `priority: unmarshaling gotoon.priority: unknown priority "urgent"`},
+		{"toon unmarshaler", "total: 5", &order{}, "total: unmarshaling gotoon.money: expected a string, got int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Unmarshal(tt.toon, tt.target)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Package mcp returns TOON from Model Context Protocol (MCP) tools. It turns
+// tool handler results into MCP text content, using TOON when it measurably
+// saves space over JSON and prefixing it with a short hint that explains the
+// format to the model. Server is a minimal stdio JSON-RPC server for tools,
+// enough to run a tool server or to test handlers against a local client.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/b92c/gotoon"
+)
+
+// DefaultHint explains TOON to the model ahead of TOON tool results.
+const DefaultHint = "Result in TOON format: key: value lines, indentation nests objects, " +
+	"and name[N]{col1,col2}: starts a table of N rows with one comma-separated row per line."
+
+// Content is an MCP content block.
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// CallToolResult is the result of an MCP tools/call request.
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text returns the text of all content blocks, one per line.
+func (r *CallToolResult) Text() string {
+	texts := make([]string, len(r.Content))
+	for i, content := range r.Content {
+		texts[i] = content.Text
+	}
+	return strings.Join(texts, "\n")
+}
+
+// ResultEncoder turns tool data into results. The zero value is ready to use.
+type ResultEncoder struct {
+	// Config configures the TOON encoder. Nil means gotoon.DefaultConfig.
+	Config *gotoon.Config
+
+	// MinSavings is the fraction of the JSON size TOON must save to be used,
+	// e.g. 0.1 for 10%. With zero, TOON is used whenever it is shorter.
+	MinSavings float64
+
+	// Hint is sent as a content block ahead of TOON text. Empty means
+	// DefaultHint.
+	Hint string
+
+	// OmitHint sends TOON text without a hint.
+	OmitHint bool
+}
+
+// DataHandler handles a tool call and returns its data, e.g. a slice of
+// records, or an error to report to the model.
+type DataHandler func(ctx context.Context, args map[string]any) (any, error)
+
+// ToolHandler handles a tool call and returns its MCP result. A nil result
+// without an error is sent as a result with no content.
+type ToolHandler func(ctx context.Context, args map[string]any) (*CallToolResult, error)
+
+var defaultResultEncoder = &ResultEncoder{}
+
+// NewResult converts data to a result with the default ResultEncoder.
+func NewResult(data any) (*CallToolResult, error) {
+	return defaultResultEncoder.Result(data)
+}
+
+// Wrap adapts a DataHandler with the default ResultEncoder.
+func Wrap(handler DataHandler) ToolHandler {
+	return defaultResultEncoder.Wrap(handler)
+}
+
+// ErrorResult reports err to the model as a tool error.
+func ErrorResult(err error) *CallToolResult {
+	return &CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}
+}
+
+// Result converts data to a result. Data of any JSON-marshalable type is
+// accepted; TOON is written by the gotoon encoder, so structs follow toon tags
+// and ToonMarshaler. The TOON and JSON encodings are measured, and TOON is used, with
+// the hint ahead of it, when the two together save at least MinSavings over
+// JSON; otherwise the result holds compact JSON. Small payloads therefore stay
+// JSON. Strings are sent as they are.
+func (e *ResultEncoder) Result(data any) (*CallToolResult, error) {
+	if text, ok := data.(string); ok {
+		return &CallToolResult{Content: []Content{{Type: "text", Text: text}}}, nil
+	}
+
+	jsonText, toon, err := e.encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	hint := e.hint()
+	if !e.useTOON(len(jsonText), len(hint)+len(toon)) {
+		return &CallToolResult{Content: []Content{{Type: "text", Text: string(jsonText)}}}, nil
+	}
+
+	result := &CallToolResult{}
+	if hint != "" {
+		result.Content = append(result.Content, Content{Type: "text", Text: hint})
+	}
+	result.Content = append(result.Content, Content{Type: "text", Text: toon})
+	return result, nil
+}
+
+// Wrap adapts a DataHandler to a ToolHandler. Handler errors become tool
+// error results, which the model can read and react to.
+func (e *ResultEncoder) Wrap(handler DataHandler) ToolHandler {
+	return func(ctx context.Context, args map[string]any) (*CallToolResult, error) {
+		data, err := handler(ctx, args)
+		if err != nil {
+			return ErrorResult(err), nil
+		}
+		return e.Result(data)
+	}
+}
+
+// encode returns the compact JSON and TOON encodings of data. Each is written
+// by its own encoder, so the TOON side follows toon tags and ToonMarshaler.
+func (e *ResultEncoder) encode(data any) ([]byte, string, error) {
+	jsonText, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("mcp: %w", err)
+	}
+
+	toon, err := gotoon.NewEncoder(e.Config).Encode(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return jsonText, toon, nil
+}
+
+// hint returns the hint to send ahead of TOON, or "" when it is omitted.
+func (e *ResultEncoder) hint() string {
+	switch {
+	case e.OmitHint:
+		return ""
+	case e.Hint != "":
+		return e.Hint
+	default:
+		return DefaultHint
+	}
+}
+
+// useTOON reports whether TOON saves enough over JSON.
+func (e *ResultEncoder) useTOON(jsonLen, toonLen int) bool {
+	if jsonLen == 0 || toonLen >= jsonLen {
+		return false
+	}
+	return float64(jsonLen-toonLen)/float64(jsonLen) >= e.MinSavings
+}
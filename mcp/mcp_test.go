@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/b92c/gotoon"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+func users() []user {
+	roles := []string{"admin", "editor", "viewer"}
+	list := make([]user, 20)
+	for i := range list {
+		list[i] = user{ID: i + 1, Name: fmt.Sprintf("user%d", i+1), Role: roles[i%3]}
+	}
+	return list
+}
+
+func TestResultUsesTOONWhenItSaves(t *testing.T) {
+	result, err := NewResult(users())
+	if err != nil {
+		t.Fatalf("NewResult failed: %v", err)
+	}
+
+	if len(result.Content) != 2 || result.Content[0].Text != DefaultHint {
+		t.Fatalf("Expected the hint ahead of the data, got %+v", result.Content)
+	}
+	expected := "items[20]{id,name,role}:\n  1,user1,admin\n  2,user2,editor\n"
+	if result.Content[1].Type != "text" || !strings.HasPrefix(result.Content[1].Text, expected) {
+		t.Errorf("Unexpected TOON content:\n%s", result.Content[1].Text)
+	}
+}
+
+func TestResultFallsBackToJSON(t *testing.T) {
+	result, err := NewResult(map[string]any{"ok": true})
+	if err != nil {
+		t.Fatalf("NewResult failed: %v", err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != `{"ok":true}` {
+		t.Errorf("Expected compact JSON, got %+v", result.Content)
+	}
+
+	strict := &ResultEncoder{MinSavings: 0.9}
+	result, _ = strict.Result(users())
+	if !strings.HasPrefix(result.Text(), `[{"id":1,`) {
+		t.Errorf("Expected JSON below MinSavings, got:\n%s", result.Text())
+	}
+}
+
+// money is written as "<cents> <currency>" in TOON.
+type money struct {
+	Cents    int64
+	Currency string
+}
+
+func (m money) MarshalTOON() (any, error) {
+	return fmt.Sprintf("%d %s", m.Cents, m.Currency), nil
+}
+
+func TestResultUsesToonMarshaler(t *testing.T) {
+	type payment struct {
+		ID     int   `toon:"id"`
+		Amount money `toon:"amount"`
+	}
+	payments := make([]payment, 10)
+	for i := range payments {
+		payments[i] = payment{ID: i + 1, Amount: money{Cents: int64(100 * (i + 1)), Currency: "EUR"}}
+	}
+
+	encoder := &ResultEncoder{OmitHint: true}
+	result, err := encoder.Result(payments)
+	if err != nil {
+		t.Fatalf("Result failed: %v", err)
+	}
+	expected := "items[10]{amount,id}:\n  100 EUR,1\n  200 EUR,2\n"
+	if !strings.HasPrefix(result.Text(), expected) {
+		t.Errorf("Unexpected TOON content:\n%s", result.Text())
+	}
+}
+
+func TestResultEncoderOptions(t *testing.T) {
+	config := gotoon.DefaultConfig()
+	config.KeyAliases = map[string]string{"name": "n"}
+
+	encoder := &ResultEncoder{Config: config, Hint: "TOON follows."}
+	result, _ := encoder.Result(users())
+	if result.Content[0].Text != "TOON follows." || !strings.Contains(result.Content[1].Text, "{id,n,role}") {
+		t.Errorf("Expected the custom hint and config, got %+v", result.Content)
+	}
+
+	encoder.OmitHint = true
+	result, _ = encoder.Result(users())
+	if len(result.Content) != 1 || !strings.HasPrefix(result.Text(), "items[20]") {
+		t.Errorf("Expected TOON without a hint, got %+v", result.Content)
+	}
+
+	result, _ = encoder.Result("plain text")
+	if result.Text() != "plain text" {
+		t.Errorf("Expected strings to pass through, got %q", result.Text())
+	}
+}
+
+func TestWrap(t *testing.T) {
+	handler := Wrap(func(ctx context.Context, args map[string]any) (any, error) {
+		if args["fail"] == true {
+			return nil, errors.New("database unavailable")
+		}
+		return users(), nil
+	})
+
+	result, err := handler(context.Background(), nil)
+	if err != nil || result.IsError || !strings.Contains(result.Text(), "1,user1,admin") {
+		t.Errorf("Unexpected result %+v, %v", result, err)
+	}
+
+	result, err = handler(context.Background(), map[string]any{"fail": true})
+	if err != nil || !result.IsError || result.Text() != "database unavailable" {
+		t.Errorf("Expected a tool error result, got %+v, %v", result, err)
+	}
+}
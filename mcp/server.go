@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the MCP revision Server implements.
+const ProtocolVersion = "2025-06-18"
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Tool describes a tool offered by a Server.
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// InputSchema is the JSON Schema of the tool arguments. Nil means an
+	// object with any properties.
+	InputSchema any `json:"inputSchema"`
+}
+
+// Server is a minimal MCP server offering tools over the stdio transport:
+// newline-delimited JSON-RPC 2.0 messages. It answers initialize, ping,
+// tools/list and tools/call, and handles requests one at a time.
+type Server struct {
+	name     string
+	version  string
+	tools    []Tool
+	handlers map[string]ToolHandler
+}
+
+// NewServer creates a Server that introduces itself with name and version.
+func NewServer(name, version string) *Server {
+	return &Server{name: name, version: version, tools: []Tool{}, handlers: make(map[string]ToolHandler)}
+}
+
+// AddTool registers a tool. Adding a tool with the name of an existing one
+// replaces it.
+func (s *Server) AddTool(tool Tool, handler ToolHandler) {
+	if tool.InputSchema == nil {
+		tool.InputSchema = map[string]any{"type": "object"}
+	}
+
+	if _, ok := s.handlers[tool.Name]; ok {
+		for i := range s.tools {
+			if s.tools[i].Name == tool.Name {
+				s.tools[i] = tool
+			}
+		}
+	} else {
+		s.tools = append(s.tools, tool)
+	}
+	s.handlers[tool.Name] = handler
+}
+
+// Serve reads requests from r and writes responses to w until r is exhausted,
+// ctx is done or writing fails. It returns nil at the end of r.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	decoder := json.NewDecoder(r)
+	encoder := json.NewEncoder(w)
+
+	for ctx.Err() == nil {
+		var msg json.RawMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var syntaxErr *json.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				return err
+			}
+			// The stream can't be resynchronized after malformed JSON.
+			encoder.Encode(errorResponse(nil, codeParseError, "parse error: %v", err))
+			return err
+		}
+
+		if resp := s.handle(ctx, msg); resp != nil {
+			if err := encoder.Encode(resp); err != nil {
+				return err
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+// request is a JSON-RPC request or notification.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorResponse builds an error response. A nil id is sent as null.
+func errorResponse(id json.RawMessage, code int, format string, args ...any) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+// handle answers one message. Notifications get no response.
+func (s *Server) handle(ctx context.Context, msg json.RawMessage) *response {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+	if req.ID == nil {
+		return nil
+	}
+
+	var result any
+	switch req.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": s.name, "version": s.version},
+		}
+	case "ping":
+		result = map[string]any{}
+	case "tools/list":
+		result = map[string]any{"tools": s.tools}
+	case "tools/call":
+		var params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return errorResponse(req.ID, codeInvalidParams, "invalid params: %v", err)
+		}
+
+		handler, ok := s.handlers[params.Name]
+		if !ok {
+			return errorResponse(req.ID, codeInvalidParams, "unknown tool %q", params.Name)
+		}
+
+		callResult, err := handler(ctx, params.Arguments)
+		switch {
+		case err != nil:
+			callResult = ErrorResult(err)
+		case callResult == nil:
+			// A result is required, so nothing to report is an empty one.
+			callResult = &CallToolResult{Content: []Content{}}
+		}
+		result = callResult
+	default:
+		return errorResponse(req.ID, codeMethodNotFound, "method %q not found", req.Method)
+	}
+
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
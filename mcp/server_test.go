@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// stubClient talks to a Server over in-memory stdio pipes, the way an MCP
+// host talks to a tool server process.
+type stubClient struct {
+	t      *testing.T
+	stdin  *io.PipeWriter
+	stdout *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func startServer(t *testing.T, server *Server) *stubClient {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	client := &stubClient{t: t, stdin: inW, stdout: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := server.Serve(context.Background(), inR, outW)
+		outW.Close()
+		client.done <- err
+	}()
+
+	t.Cleanup(func() {
+		inW.Close()
+		if err := <-client.done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+	return client
+}
+
+// send writes a raw message line.
+func (c *stubClient) send(msg string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.stdin, msg+"\n"); err != nil {
+		c.t.Fatalf("Writing to server failed: %v", err)
+	}
+}
+
+// receive reads one response line.
+func (c *stubClient) receive() map[string]any {
+	c.t.Helper()
+	line, err := c.stdout.ReadString('\n')
+	if err != nil {
+		c.t.Fatalf("Reading from server failed: %v", err)
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal([]byte(line), &resp); err != nil {
+		c.t.Fatalf("Invalid response %q: %v", line, err)
+	}
+	return resp
+}
+
+// call sends a request and returns its result, failing on a JSON-RPC error.
+func (c *stubClient) call(method string, params any) map[string]any {
+	c.t.Helper()
+	resp := c.request(method, params)
+	if resp["error"] != nil {
+		c.t.Fatalf("%s failed: %v", method, resp["error"])
+	}
+	return resp["result"].(map[string]any)
+}
+
+// request sends a request and returns the whole response.
+func (c *stubClient) request(method string, params any) map[string]any {
+	c.t.Helper()
+	c.nextID++
+	msg, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+	c.send(string(msg))
+
+	resp := c.receive()
+	if resp["id"] != float64(c.nextID) {
+		c.t.Fatalf("Expected response to request %d, got %v", c.nextID, resp)
+	}
+	return resp
+}
+
+func newUserServer() *Server {
+	server := NewServer("users", "1.0.0")
+	server.AddTool(Tool{Name: "list_users", Description: "List users"},
+		Wrap(func(ctx context.Context, args map[string]any) (any, error) {
+			if role, ok := args["role"].(string); ok {
+				return nil, fmt.Errorf("no users with role %q", role)
+			}
+			return users(), nil
+		}))
+	return server
+}
+
+func TestServerSession(t *testing.T) {
+	client := startServer(t, newUserServer())
+
+	init := client.call("initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "stub", "version": "0"},
+	})
+	if init["protocolVersion"] != ProtocolVersion || init["serverInfo"].(map[string]any)["name"] != "users" {
+		t.Errorf("Unexpected initialize result: %v", init)
+	}
+	client.send(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+
+	tools := client.call("tools/list", nil)["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "list_users" {
+		t.Fatalf("Unexpected tools: %v", tools)
+	}
+	if schema := tools[0].(map[string]any)["inputSchema"]; schema.(map[string]any)["type"] != "object" {
+		t.Errorf("Expected a default input schema, got %v", schema)
+	}
+
+	result := client.call("tools/call", map[string]any{"name": "list_users", "arguments": map[string]any{}})
+	content := result["content"].([]any)
+	if len(content) != 2 || !strings.HasPrefix(content[1].(map[string]any)["text"].(string), "items[20]{id,name,role}:") {
+		t.Errorf("Expected TOON tool content, got %v", content)
+	}
+
+	result = client.call("tools/call", map[string]any{"name": "list_users", "arguments": map[string]any{"role": "owner"}})
+	if result["isError"] != true {
+		t.Errorf("Expected a tool error result, got %v", result)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	client := startServer(t, newUserServer())
+
+	tests := []struct {
+		method string
+		params any
+		code   float64
+	}{
+		{"tools/call", map[string]any{"name": "drop_tables"}, codeInvalidParams},
+		{"resources/list", nil, codeMethodNotFound},
+	}
+
+	for _, test := range tests {
+		resp := client.request(test.method, test.params)
+		rpcErr, ok := resp["error"].(map[string]any)
+		if !ok || rpcErr["code"] != test.code {
+			t.Errorf("%s: expected error code %v, got %v", test.method, test.code, resp)
+		}
+	}
+
+	if result := client.call("ping", nil); len(result) != 0 {
+		t.Errorf("Expected an empty ping result, got %v", result)
+	}
+}
+
+func TestServerNilToolResult(t *testing.T) {
+	server := NewServer("noop", "1.0.0")
+	server.AddTool(Tool{Name: "noop"}, func(ctx context.Context, args map[string]any) (*CallToolResult, error) {
+		return nil, nil
+	})
+	client := startServer(t, server)
+
+	resp := client.request("tools/call", map[string]any{"name": "noop"})
+	result, ok := resp["result"].(map[string]any)
+	if !ok || resp["error"] != nil {
+		t.Fatalf("Expected a result, got %v", resp)
+	}
+	if content, ok := result["content"].([]any); !ok || len(content) != 0 {
+		t.Errorf("Expected empty content, got %v", result)
+	}
+}
@@ -0,0 +1,491 @@
+package gotoon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultNDJSONSampleSize is the number of records read to infer columns.
+	defaultNDJSONSampleSize = 1000
+
+	// defaultNDJSONBatchSize is the number of rows decoded at a time.
+	defaultNDJSONBatchSize = 1000
+)
+
+// topLevelTablePattern matches a top-level table header and captures its row
+// count.
+var topLevelTablePattern = regexp.MustCompile(`^items\[(\d+)\]\{[^\}]*\}(?:@\{.*\})?:$`)
+
+// NDJSONOptions configures FromNDJSON and ToNDJSON. A nil *NDJSONOptions uses
+// the defaults.
+type NDJSONOptions struct {
+	// Config configures the encoder of FromNDJSON and the decoder of
+	// ToNDJSON. Nil means DefaultConfig.
+	Config *Config
+
+	// SampleSize is the number of records FromNDJSON reads to infer the
+	// table columns. Zero means 1000.
+	SampleSize int
+
+	// DropUnknown makes FromNDJSON drop fields that first appear after the
+	// sample instead of failing.
+	DropUnknown bool
+
+	// BatchSize is the number of rows ToNDJSON decodes at a time. Zero means
+	// 1000.
+	BatchSize int
+
+	// TempDir is where FromNDJSON spools rows until it knows their count.
+	// Empty means os.TempDir.
+	TempDir string
+}
+
+// config returns the configured Config or the default.
+func (o *NDJSONOptions) config() *Config {
+	if o == nil || o.Config == nil {
+		return DefaultConfig()
+	}
+	return o.Config
+}
+
+// FromNDJSON converts JSON Lines, one object per line, to a single TOON table.
+// Columns are the dot paths of the first SampleSize records, as flattened by
+// ArrayFlattener. Rows are encoded as they are read and spooled to a
+// temporary file, because the header needs the row count, so memory stays
+// bounded by the sample. JSON columns are the ones holding an array or object
+// in the sample, and every cell of them is JSON-encoded; an array or object
+// first seen in another column after the sample is an error. Column types and
+// sparse columns are detected over all records. DictionaryEncoding, HoistConstantColumns and
+// NormalizeObjects need every row at once and are not applied. Transformers
+// see each record as a list item, at the path "*".
+func FromNDJSON(w io.Writer, r io.Reader, opts *NDJSONOptions) error {
+	encoder := NewEncoder(opts.config())
+	records := &ndjsonReader{r: bufio.NewReader(r), encoder: encoder}
+
+	sampleSize := defaultNDJSONSampleSize
+	if opts != nil && opts.SampleSize > 0 {
+		sampleSize = opts.SampleSize
+	}
+
+	var sample []any
+	for len(sample) < sampleSize {
+		record, err := records.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		sample = append(sample, record)
+	}
+
+	tempDir := ""
+	if opts != nil {
+		tempDir = opts.TempDir
+	}
+	spool, err := os.CreateTemp(tempDir, "gotoon-ndjson-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	table := newStreamTable(encoder, encoder.flattener.extractColumns(sample), sample, bufio.NewWriter(spool))
+	for _, record := range sample {
+		if err := table.writeRow(record); err != nil {
+			return err
+		}
+	}
+	sample = nil
+
+	dropUnknown := opts != nil && opts.DropUnknown
+	for {
+		record, err := records.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if !dropUnknown {
+			if column := table.unknownColumn(record); column != "" {
+				return fmt.Errorf("line %d: field %q is not in the first %d records; raise SampleSize or set DropUnknown", records.line, column, sampleSize)
+			}
+		}
+		if err := table.writeRow(record); err != nil {
+			return fmt.Errorf("line %d: %w", records.line, err)
+		}
+	}
+
+	if err := table.out.Flush(); err != nil {
+		return err
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, table.header()+"\n"); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, spool)
+	return err
+}
+
+// ndjsonReader reads one JSON object per non-blank line, applying the
+// transformers of encoder to each as a list item.
+type ndjsonReader struct {
+	r       *bufio.Reader
+	encoder *Encoder
+	line    int
+}
+
+// next returns the next record, or io.EOF after the last one.
+func (n *ndjsonReader) next() (any, error) {
+	for {
+		data, err := n.r.ReadBytes('\n')
+		if len(data) == 0 && err != nil {
+			return nil, err
+		}
+		n.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		var record any
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", n.line, err)
+		}
+		if decoder.More() {
+			return nil, fmt.Errorf("line %d: expected one JSON value per line", n.line)
+		}
+		if _, ok := record.(map[string]any); !ok {
+			return nil, fmt.Errorf("line %d: expected a JSON object", n.line)
+		}
+		record, err = n.encoder.transform(normalizeJSON(record), "*")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n.line, err)
+		}
+		return record, nil
+	}
+}
+
+// streamTable writes table rows as they arrive and tracks what the header
+// needs to declare about each column. JSON columns are fixed up front, since
+// their cells are written JSON-encoded.
+type streamTable struct {
+	encoder *Encoder
+	columns []string
+	known   map[string]bool
+	out     *bufio.Writer
+	rows    int
+
+	types    []string
+	untyped  []bool
+	json     []bool
+	optional []bool
+}
+
+// newStreamTable creates a streamTable writing rows to out, with JSON columns
+// taken from sample.
+func newStreamTable(encoder *Encoder, columns []string, sample []any, out *bufio.Writer) *streamTable {
+	known := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		known[col] = true
+	}
+
+	rows := make([][]any, len(sample))
+	for i, record := range sample {
+		rows[i], _ = encoder.flattener.flattenRow(record, columns)
+	}
+	jsonColumns := make([]bool, len(columns))
+	for j := range columns {
+		jsonColumns[j] = isJSONColumn(rows, j)
+	}
+
+	return &streamTable{
+		encoder:  encoder,
+		columns:  columns,
+		known:    known,
+		out:      out,
+		types:    make([]string, len(columns)),
+		untyped:  make([]bool, len(columns)),
+		json:     jsonColumns,
+		optional: make([]bool, len(columns)),
+	}
+}
+
+// unknownColumn returns the first column of record that is not in the table,
+// or "".
+func (t *streamTable) unknownColumn(record any) string {
+	for _, col := range t.encoder.flattener.extractColumns([]any{record}) {
+		if !t.known[col] {
+			return col
+		}
+	}
+	return ""
+}
+
+// writeRow writes record as a row. Missing values are left empty; with
+// SparseTables their column is marked optional and nil values are written as
+// null to tell them apart. Cells of JSON columns are JSON-encoded, and an
+// array or object in any other column is an error.
+func (t *streamTable) writeRow(record any) error {
+	sparse := t.encoder.config.SparseTables
+	row, missing := t.encoder.flattener.flattenRow(record, t.columns)
+
+	cells := make([]string, len(row))
+	for j, value := range row {
+		switch {
+		case missing[j]:
+			t.optional[j] = t.optional[j] || sparse
+			continue
+		case value == nil:
+			if sparse {
+				cells[j] = "null"
+			}
+			continue
+		}
+
+		if t.json[j] {
+			cells[j] = t.encoder.jsonCell(value)
+			continue
+		}
+		switch value.(type) {
+		case []any, map[string]any:
+			return fmt.Errorf("field %q holds an array or object but only plain values in the sample; raise SampleSize", t.columns[j])
+		}
+		if !t.untyped[j] {
+			var ok bool
+			t.types[j], ok = mergeColumnType(t.types[j], valueType(value))
+			t.untyped[j] = !ok
+		}
+		cells[j] = t.encoder.escapeScalar(value)
+	}
+
+	t.rows++
+	_, err := t.out.WriteString("  " + strings.Join(cells, ",") + "\n")
+	return err
+}
+
+// header returns the table header for the rows written so far.
+func (t *streamTable) header() string {
+	config := t.encoder.config
+	formatted := make([]string, len(t.columns))
+	for j, col := range t.columns {
+		formatted[j] = config.formatKey(col)
+		if t.optional[j] {
+			formatted[j] += optionalSuffix
+		}
+		switch {
+		case t.json[j]:
+			formatted[j] += ":" + jsonColumnType
+		case config.TypedHeaders && !t.untyped[j] && t.types[j] != "":
+			formatted[j] += ":" + t.types[j]
+		}
+	}
+	return fmt.Sprintf("items[%d]{%s}:", t.rows, strings.Join(formatted, ","))
+}
+
+// ToNDJSON converts a TOON document holding a top-level table to JSON Lines,
+// one object per row. The table is read line by line and decoded BatchSize
+// rows at a time, so memory stays bounded by the batch plus the table's
+// dictionaries and reference tables. In strict mode the row count declared by
+// the header must match.
+func ToNDJSON(w io.Writer, r io.Reader, opts *NDJSONOptions) error {
+	config := opts.config()
+	batchSize := defaultNDJSONBatchSize
+	if opts != nil && opts.BatchSize > 0 {
+		batchSize = opts.BatchSize
+	}
+
+	stream := &tableStream{
+		decoder: NewDecoder(config),
+		lines:   bufio.NewReader(r),
+		out:     bufio.NewWriter(w),
+	}
+	if err := stream.readHeader(); err != nil {
+		return err
+	}
+
+	for {
+		done, err := stream.readBatch(batchSize)
+		if err != nil {
+			return err
+		}
+		if err := stream.flush(); err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+
+	if config.Strict && stream.rows != stream.count {
+		return &SyntaxError{Line: stream.headerLine, Column: 1, Msg: fmt.Sprintf("table declares %d rows, found %d", stream.count, stream.rows)}
+	}
+	if config.Strict && stream.trailing > 0 {
+		return &SyntaxError{Line: stream.trailing, Column: 1, Msg: "unexpected content after top-level block"}
+	}
+	return stream.out.Flush()
+}
+
+// tableStream reads a top-level table in batches of rows. Each batch is
+// decoded as a table of its own made of the header, the dictionary and
+// reference table lines, and the batch rows.
+type tableStream struct {
+	decoder *Decoder
+	lines   *bufio.Reader
+	out     *bufio.Writer
+	line    int
+	eof     bool
+
+	header     string
+	headerLine int
+	count      int
+	rowIndent  int
+	prelude    []string
+	preludeAt  []int
+	batch      []string
+	batchAt    []int
+	rows       int
+	trailing   int
+}
+
+// readLine returns the next line without its newline.
+func (s *tableStream) readLine() (string, bool, error) {
+	if s.eof {
+		return "", false, nil
+	}
+	text, err := s.lines.ReadString('\n')
+	if err == io.EOF {
+		s.eof = true
+		if text == "" {
+			return "", false, nil
+		}
+	} else if err != nil {
+		return "", false, err
+	}
+	s.line++
+	return strings.TrimRight(text, "\r\n"), true, nil
+}
+
+// readHeader reads up to the table header, skipping blank and comment lines.
+func (s *tableStream) readHeader() error {
+	for {
+		text, ok, err := s.readLine()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("no top-level table found")
+		}
+
+		content := strings.TrimSpace(text)
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+
+		match := topLevelTablePattern.FindStringSubmatch(text)
+		if match == nil {
+			return &SyntaxError{Line: s.line, Column: lineIndent(text) + 1, Msg: "expected a top-level table header such as items[N]{...}:"}
+		}
+		s.header, s.headerLine, s.rowIndent = text, s.line, -1
+		s.count, _ = strconv.Atoi(match[1])
+		return nil
+	}
+}
+
+// readBatch reads up to size rows. Dictionary and reference table lines
+// before the first row are kept for every batch. It reports whether the table
+// has ended.
+func (s *tableStream) readBatch(size int) (bool, error) {
+	for len(s.batch) < size {
+		text, ok, err := s.readLine()
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return true, nil
+		}
+
+		content := strings.TrimSpace(text)
+		if content == "" {
+			continue
+		}
+
+		indent := lineIndent(text)
+		if s.rowIndent < 0 {
+			s.rowIndent = indent
+		}
+		if indent == 0 || indent < s.rowIndent {
+			if !strings.HasPrefix(content, "#") {
+				s.trailing = s.line
+			}
+			s.eof = true
+			return true, nil
+		}
+
+		isPrelude := s.rows == 0 && len(s.batch) == 0 &&
+			(indent > s.rowIndent || strings.HasPrefix(content, "&") || strings.HasPrefix(content, referencePrefix))
+		if isPrelude {
+			s.prelude = append(s.prelude, text)
+			s.preludeAt = append(s.preludeAt, s.line)
+			continue
+		}
+
+		s.batch = append(s.batch, text)
+		s.batchAt = append(s.batchAt, s.line)
+	}
+	return false, nil
+}
+
+// flush decodes the current batch and writes its rows as JSON lines.
+func (s *tableStream) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	// The header starts with "items[", so only its count changes.
+	header := "items[" + strconv.Itoa(len(s.batch)) + s.header[strings.Index(s.header, "]"):]
+	lines := append(append([]string{header}, s.prelude...), s.batch...)
+	decoded, err := s.decoder.Decode(strings.Join(lines, "\n"))
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) && syntaxErr.Line >= 1 && syntaxErr.Line <= len(lines) {
+			at := append(append([]int{s.headerLine}, s.preludeAt...), s.batchAt...)
+			return &SyntaxError{Line: at[syntaxErr.Line-1], Column: syntaxErr.Column, Msg: syntaxErr.Msg}
+		}
+		return err
+	}
+
+	encoder := json.NewEncoder(s.out)
+	encoder.SetEscapeHTML(false)
+	items, _ := decoded["items"].([]any)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	s.rows += len(s.batch)
+	s.batch, s.batchAt = s.batch[:0], s.batchAt[:0]
+	return nil
+}
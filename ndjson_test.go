@@ -0,0 +1,175 @@
+package gotoon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const ndjsonEvents = `{"level":"info","msg":"started","user":{"id":1}}
+
+{"level":"warn","msg":"slow, retrying","user":{"id":2},"tags":["db"]}
+{"level":"info","msg":"done","user":{"id":1},"ms":12.5}
+`
+
+func TestFromNDJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := FromNDJSON(&out, strings.NewReader(ndjsonEvents), nil); err != nil {
+		t.Fatalf("FromNDJSON failed: %v", err)
+	}
+
+	expected := "items[3]{level,msg,user.id,tags:json,ms}:\n" +
+		"  info,started,1,,\n" +
+		"  warn,slow\\, retrying,2,[\"db\"],\n" +
+		"  info,done,1,,12.5\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestFromNDJSONTypedSparse(t *testing.T) {
+	config := DefaultConfig()
+	config.TypedHeaders = true
+	config.SparseTables = true
+
+	input := `{"id":1,"note":null}` + "\n" + `{"id":2.5}` + "\n"
+
+	var out bytes.Buffer
+	if err := FromNDJSON(&out, strings.NewReader(input), &NDJSONOptions{Config: config}); err != nil {
+		t.Fatalf("FromNDJSON failed: %v", err)
+	}
+
+	expected := "items[2]{id:float,note?}:\n  1,null\n  2.5,\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+
+	var back bytes.Buffer
+	if err := ToNDJSON(&back, &out, &NDJSONOptions{Config: config}); err != nil {
+		t.Fatalf("ToNDJSON failed: %v", err)
+	}
+	if back.String() != "{\"id\":1,\"note\":null}\n{\"id\":2.5}\n" {
+		t.Errorf("Unexpected NDJSON:\n%s", back.String())
+	}
+}
+
+func TestFromNDJSONSampleWindow(t *testing.T) {
+	input := `{"id":1}` + "\n" + `{"id":2,"extra":true}` + "\n"
+
+	err := FromNDJSON(&bytes.Buffer{}, strings.NewReader(input), &NDJSONOptions{SampleSize: 1})
+	if err == nil || !strings.Contains(err.Error(), `line 2: field "extra" is not in the first 1 records`) {
+		t.Errorf("Expected an unknown field error, got %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := FromNDJSON(&out, strings.NewReader(input), &NDJSONOptions{SampleSize: 1, DropUnknown: true}); err != nil {
+		t.Fatalf("FromNDJSON failed: %v", err)
+	}
+	if out.String() != "items[2]{id}:\n  1\n  2\n" {
+		t.Errorf("Unexpected TOON:\n%s", out.String())
+	}
+}
+
+func TestFromNDJSONJSONColumns(t *testing.T) {
+	input := `{"id":1,"a":"x"}` + "\n" + `{"id":2,"a":[1]}` + "\n" + `{"id":3,"a":7}` + "\n" + `{"id":4}` + "\n"
+
+	var out bytes.Buffer
+	if err := FromNDJSON(&out, strings.NewReader(input), nil); err != nil {
+		t.Fatalf("FromNDJSON failed: %v", err)
+	}
+	expected := "items[4]{a:json,id}:\n  \"x\",1\n  [1],2\n  7,3\n  ,4\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+
+	config := DefaultConfig()
+	config.Strict = true
+	var back bytes.Buffer
+	if err := ToNDJSON(&back, &out, &NDJSONOptions{Config: config}); err != nil {
+		t.Fatalf("Strict ToNDJSON failed: %v", err)
+	}
+	if back.String() != "{\"a\":\"x\",\"id\":1}\n{\"a\":[1],\"id\":2}\n{\"a\":7,\"id\":3}\n{\"a\":null,\"id\":4}\n" {
+		t.Errorf("Unexpected NDJSON:\n%s", back.String())
+	}
+
+	err := FromNDJSON(&bytes.Buffer{}, strings.NewReader(input), &NDJSONOptions{SampleSize: 1})
+	if err == nil || !strings.Contains(err.Error(), `line 2: field "a" holds an array or object`) {
+		t.Errorf("Expected a late JSON column error, got %v", err)
+	}
+}
+
+func TestFromNDJSONErrors(t *testing.T) {
+	tests := map[string]string{
+		"{\"id\":1}\n[1,2]\n":    "line 2: expected a JSON object",
+		"{\"id\":1}\n{\"id\":\n": "line 2: invalid JSON",
+		"{\"id\":1} {}\n":        "line 1: expected one JSON value per line",
+	}
+	for input, want := range tests {
+		err := FromNDJSON(&bytes.Buffer{}, strings.NewReader(input), nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestToNDJSON(t *testing.T) {
+	input := "# events\nitems[3]{level,user.id,tags:json}@{app=api}:\n" +
+		"  &level: info,warn\n" +
+		"  0,1,\n" +
+		"  1,2,[\"db\"]\n" +
+		"  0,1,[]\n"
+
+	var out bytes.Buffer
+	if err := ToNDJSON(&out, strings.NewReader(input), &NDJSONOptions{BatchSize: 2}); err != nil {
+		t.Fatalf("ToNDJSON failed: %v", err)
+	}
+
+	expected := `{"app":"api","level":"info","tags":null,"user":{"id":1}}` + "\n" +
+		`{"app":"api","level":"warn","tags":["db"],"user":{"id":2}}` + "\n" +
+		`{"app":"api","level":"info","tags":[],"user":{"id":1}}` + "\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected NDJSON.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestToNDJSONStrict(t *testing.T) {
+	config := DefaultConfig()
+	config.Strict = true
+
+	tests := map[string]string{
+		"items[3]{id}:\n  1\n  2\n":         "line 1, column 1: table declares 3 rows, found 2",
+		"items[2]{id:int}:\n  1\n  x\n":     "line 3, column 3",
+		"items[1]{id}:\n  1\nextra: true\n": "line 3, column 1: unexpected content",
+		"# no table\nname: Alice\n":         "line 2, column 1: expected a top-level table header",
+	}
+	for input, want := range tests {
+		err := ToNDJSON(&bytes.Buffer{}, strings.NewReader(input), &NDJSONOptions{Config: config, BatchSize: 1})
+
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestNDJSONRoundTripLarge(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 2500; i++ {
+		fmt.Fprintf(&input, "{\"id\":%d,\"name\":\"user %d\"}\n", i, i)
+	}
+
+	var toon bytes.Buffer
+	if err := FromNDJSON(&toon, strings.NewReader(input.String()), &NDJSONOptions{SampleSize: 10}); err != nil {
+		t.Fatalf("FromNDJSON failed: %v", err)
+	}
+
+	var back bytes.Buffer
+	if err := ToNDJSON(&back, &toon, nil); err != nil {
+		t.Fatalf("ToNDJSON failed: %v", err)
+	}
+	if back.String() != input.String() {
+		t.Errorf("Round trip changed the records")
+	}
+}
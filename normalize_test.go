@@ -0,0 +1,122 @@
+package gotoon
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRepeatedNestedObjects(t *testing.T) {
+	config := DefaultConfig()
+	config.NormalizeObjects = true
+
+	alice := map[string]any{"id": "c1", "name": "Alice", "address": map[string]any{"city": "Utrecht"}}
+	bob := map[string]any{"id": "c2", "name": "Bob", "address": map[string]any{"city": "Leiden"}}
+
+	data := map[string]any{
+		"orders": []any{
+			map[string]any{"id": "o1", "total": 9.5, "customer": alice},
+			map[string]any{"id": "o2", "total": 3.25, "customer": bob},
+			map[string]any{"id": "o3", "total": 12, "customer": alice},
+			map[string]any{"id": "o4", "total": 1.75, "customer": alice},
+		},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if !strings.Contains(toon, "items[4]{*customer,id,total}:") {
+		t.Errorf("Expected reference column in main table, got: %s", toon)
+	}
+	if !strings.Contains(toon, "*customer[2]{address.city,id,name}:") {
+		t.Errorf("Expected reference table for customers, got: %s", toon)
+	}
+	if strings.Count(toon, "Alice") != 1 {
+		t.Errorf("Expected 'Alice' to be written once, got: %s", toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	orders, ok := decoded["orders"].([]any)
+	if !ok || len(orders) != 4 {
+		t.Fatalf("Expected 4 orders, got: %+v", decoded["orders"])
+	}
+
+	third := orders[2].(map[string]any)
+	if third["id"] != "o3" || third["total"] != 12 {
+		t.Errorf("Expected order o3 with total 12, got: %+v", third)
+	}
+	if _, exists := third["*customer"]; exists {
+		t.Errorf("Reference column should be removed after join, got: %+v", third)
+	}
+
+	customer := third["customer"].(map[string]any)
+	if customer["name"] != "Alice" {
+		t.Errorf("Expected customer Alice, got: %+v", customer)
+	}
+	if customer["address"].(map[string]any)["city"] != "Utrecht" {
+		t.Errorf("Expected nested address to be restored, got: %+v", customer)
+	}
+
+	customer["name"] = "Changed"
+	first := orders[0].(map[string]any)["customer"].(map[string]any)
+	if first["name"] != "Alice" {
+		t.Errorf("Joined objects should not share state, got: %+v", first)
+	}
+}
+
+func TestNormalizeLeavesUniqueObjectsInline(t *testing.T) {
+	config := DefaultConfig()
+	config.NormalizeObjects = true
+
+	data := []any{
+		map[string]any{"id": 1, "user": map[string]any{"name": "John"}},
+		map[string]any{"id": 2, "user": map[string]any{"name": "Jane"}},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if strings.Contains(toon, "*user") {
+		t.Errorf("Expected unique objects to stay flattened, got: %s", toon)
+	}
+	if !strings.Contains(toon, "user.name") {
+		t.Errorf("Expected dot-notation column, got: %s", toon)
+	}
+}
+
+func TestNormalizeWithMissingReference(t *testing.T) {
+	config := DefaultConfig()
+	config.NormalizeObjects = true
+
+	product := map[string]any{"sku": "A-1", "name": "Widget"}
+	data := []any{
+		map[string]any{"id": 1, "product": product},
+		map[string]any{"id": 2, "product": nil},
+		map[string]any{"id": 3, "product": product},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	items := decoded["items"].([]any)
+	if items[1].(map[string]any)["product"] != nil {
+		t.Errorf("Expected nil product for second item, got: %+v", items[1])
+	}
+	if items[2].(map[string]any)["product"].(map[string]any)["sku"] != "A-1" {
+		t.Errorf("Expected product A-1 for third item, got: %+v", items[2])
+	}
+}
@@ -0,0 +1,259 @@
+package gotoon
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EstimateTokens approximates the token count of text with the common
+// four-characters-per-token heuristic.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// Prompt builds a prompt from named sections of TOON data and plain text. It
+// explains the TOON format once, ahead of the first data section, and fits the
+// sections into an optional token budget.
+type Prompt struct {
+	// Budget caps the estimated tokens of the rendered prompt, explainer
+	// included. Zero means no limit. Sections are fitted in the order they
+	// were added, so add the most important ones first: a list that doesn't
+	// fit keeps as many leading items as the remaining budget allows, and
+	// any other section that doesn't fit is left out.
+	Budget int
+
+	encoder  *Encoder
+	config   *Config
+	sections []*promptSection
+}
+
+// promptSection is a section added to a Prompt.
+type promptSection struct {
+	name   string
+	data   any
+	text   string
+	isText bool
+}
+
+// SectionStats reports the size of a rendered section.
+type SectionStats struct {
+	Name string
+
+	// Chars and Tokens measure the section as rendered, heading included.
+	Chars  int
+	Tokens int
+
+	// Items is the number of list items rendered and TotalItems the number
+	// given. They are zero for sections that don't hold a list.
+	Items      int
+	TotalItems int
+
+	// Omitted is true when the section didn't fit the budget at all.
+	Omitted bool
+}
+
+// Truncated reports whether the budget dropped some of the section's items.
+func (s SectionStats) Truncated() bool {
+	return s.Items < s.TotalItems
+}
+
+// RenderedPrompt is the output of Prompt.Render.
+type RenderedPrompt struct {
+	Text     string
+	Tokens   int
+	Sections []SectionStats
+}
+
+// String returns the prompt text.
+func (r *RenderedPrompt) String() string {
+	return r.Text
+}
+
+// NewPrompt creates a Prompt that encodes data with the given configuration.
+func NewPrompt(config *Config) *Prompt {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Prompt{encoder: NewEncoder(config), config: config}
+}
+
+// Section adds data, encoded as TOON, under a heading.
+func (p *Prompt) Section(name string, data any) *Prompt {
+	p.sections = append(p.sections, &promptSection{name: name, data: data})
+	return p
+}
+
+// Text adds plain text under a heading. Text sections are never truncated.
+func (p *Prompt) Text(name, text string) *Prompt {
+	p.sections = append(p.sections, &promptSection{name: name, text: text, isText: true})
+	return p
+}
+
+// Explainer returns the "how to read TOON" preamble for the Prompt's
+// configuration. It only mentions the features the configuration enables.
+func (p *Prompt) Explainer() string {
+	lines := []string{
+		"Data sections below use TOON, a compact JSON equivalent:",
+		"- `key: value` lines; indentation nests objects; `- ` starts a list item.",
+		"- `name[N]{a,b}:` starts a table of N rows, one comma-separated row per indented line. Dotted columns are nested keys; empty cells are null.",
+	}
+	if p.config.TypedHeaders {
+		lines = append(lines, "- `col:int` declares a column's type.")
+	}
+	if p.config.HoistConstantColumns {
+		lines = append(lines, "- `@{col=value}` after a header sets a column that is the same in every row.")
+	}
+	if p.config.DictionaryEncoding {
+		lines = append(lines, "- `&col: v0,v1` lists a column's values; its cells hold the index of a value.")
+	}
+	if p.config.SparseTables {
+		lines = append(lines, "- `col?` marks a column missing from some rows; empty cells mean the key is absent.")
+	}
+	if p.config.NormalizeObjects {
+		lines = append(lines, "- `*name[N]{..}:` is a table of shared objects that a column of the main table refers to by index.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Render encodes the sections and joins them into one string: the explainer
+// when a data section is rendered, then each section under a "## name"
+// heading. A truncated list ends with a "# showing N of M items" comment.
+func (p *Prompt) Render() (*RenderedPrompt, error) {
+	remaining := -1
+	if p.Budget > 0 {
+		remaining = p.Budget
+	}
+
+	hasData := false
+	for _, section := range p.sections {
+		hasData = hasData || !section.isText
+	}
+
+	// The explainer's tokens are reserved up front and it is dropped again
+	// when no data section fits.
+	explainer := ""
+	if hasData {
+		explainer = p.Explainer()
+		remaining = spend(remaining, explainer)
+	}
+
+	rendered := &RenderedPrompt{}
+	parts := []string{explainer}
+	dataRendered := false
+	for _, section := range p.sections {
+		text, stats, err := p.renderSection(section, remaining)
+		if err != nil {
+			return nil, err
+		}
+		rendered.Sections = append(rendered.Sections, stats)
+		if stats.Omitted {
+			continue
+		}
+		parts = append(parts, text)
+		remaining = spend(remaining, text)
+		dataRendered = dataRendered || !section.isText
+	}
+
+	if !dataRendered {
+		parts = parts[1:]
+	}
+	rendered.Text = strings.Join(parts, "\n\n")
+	rendered.Tokens = EstimateTokens(rendered.Text)
+	return rendered, nil
+}
+
+// listItems returns the items of a slice or array of any element type, so
+// typed slices such as []Order can be truncated like []any.
+func listItems(data any) ([]any, bool) {
+	if items, ok := data.([]any); ok {
+		return items, true
+	}
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
+
+// renderSection renders one section within budget tokens, or without a limit
+// when budget is negative.
+func (p *Prompt) renderSection(section *promptSection, budget int) (string, SectionStats, error) {
+	stats := SectionStats{Name: section.name}
+	heading := "## " + section.name + "\n"
+
+	fits := func(text string) bool {
+		// Sections are joined by a blank line.
+		return budget < 0 || EstimateTokens(text)+1 <= budget
+	}
+	measure := func(text string) (string, SectionStats, error) {
+		stats.Chars = len(text)
+		stats.Tokens = EstimateTokens(text)
+		return text, stats, nil
+	}
+
+	if section.isText {
+		text := heading + section.text
+		if !fits(text) {
+			stats.Omitted = true
+			return "", stats, nil
+		}
+		return measure(text)
+	}
+
+	items, isList := listItems(section.data)
+	if isList {
+		stats.Items, stats.TotalItems = len(items), len(items)
+	}
+
+	toon, err := p.encoder.Encode(section.data)
+	if err != nil {
+		return "", stats, fmt.Errorf("prompt section %q: %w", section.name, err)
+	}
+	if text := heading + toon; fits(text) {
+		return measure(text)
+	}
+	if !isList {
+		stats.Omitted = true
+		return "", stats, nil
+	}
+
+	// Find the longest prefix of the list that fits.
+	best := ""
+	low, high := 1, len(items)-1
+	for low <= high {
+		n := (low + high) / 2
+		toon, err := p.encoder.Encode(items[:n])
+		if err != nil {
+			return "", stats, fmt.Errorf("prompt section %q: %w", section.name, err)
+		}
+
+		text := fmt.Sprintf("%s%s\n# showing %d of %d items", heading, toon, n, len(items))
+		if fits(text) {
+			best, stats.Items = text, n
+			low = n + 1
+		} else {
+			high = n - 1
+		}
+	}
+
+	if best == "" {
+		stats.Items = 0
+		stats.Omitted = true
+		return "", stats, nil
+	}
+	return measure(best)
+}
+
+// spend subtracts the tokens of a part and the blank line before the next one
+// from a budget. A negative budget is unlimited.
+func spend(budget int, text string) int {
+	if budget < 0 {
+		return budget
+	}
+	return max(budget-EstimateTokens(text)-1, 0)
+}
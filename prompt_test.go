@@ -0,0 +1,136 @@
+package gotoon
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func promptOrders(n int) []any {
+	orders := make([]any, n)
+	for i := range orders {
+		orders[i] = map[string]any{"id": i + 1, "status": "shipped", "total": float64(i) + 0.5}
+	}
+	return orders
+}
+
+func TestPromptRender(t *testing.T) {
+	prompt := NewPrompt(nil).
+		Text("Task", "Summarize the customer's orders.").
+		Section("customer", map[string]any{"name": "Alice", "tier": "gold"}).
+		Section("orders", promptOrders(2))
+
+	rendered, err := prompt.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := prompt.Explainer() + "\n\n" +
+		"## Task\nSummarize the customer's orders.\n\n" +
+		"## customer\nname: Alice\ntier: gold\n\n" +
+		"## orders\nitems[2]{id,status,total}:\n  1,shipped,0.5\n  2,shipped,1.5"
+	if rendered.String() != expected {
+		t.Errorf("Unexpected prompt.\nExpected:\n%s\nGot:\n%s", expected, rendered)
+	}
+
+	if strings.Count(rendered.Text, "TOON") != 1 {
+		t.Errorf("Expected the explainer once, got:\n%s", rendered)
+	}
+	if rendered.Tokens != EstimateTokens(rendered.Text) || len(rendered.Sections) != 3 {
+		t.Errorf("Unexpected accounting: %+v", rendered)
+	}
+
+	orders := rendered.Sections[2]
+	if orders.Name != "orders" || orders.Items != 2 || orders.TotalItems != 2 || orders.Truncated() {
+		t.Errorf("Unexpected orders stats: %+v", orders)
+	}
+	if want := len("## orders\nitems[2]{id,status,total}:\n  1,shipped,0.5\n  2,shipped,1.5"); orders.Chars != want {
+		t.Errorf("Expected %d chars, got %d", want, orders.Chars)
+	}
+}
+
+func TestPromptWithoutData(t *testing.T) {
+	rendered, _ := NewPrompt(nil).Text("Task", "Say hello.").Render()
+	if rendered.Text != "## Task\nSay hello." {
+		t.Errorf("Expected no explainer without data sections, got:\n%s", rendered)
+	}
+}
+
+func TestPromptBudget(t *testing.T) {
+	prompt := NewPrompt(nil).
+		Section("user", map[string]any{"name": "Alice"}).
+		Section("orders", promptOrders(100)).
+		Section("notes", map[string]any{"text": strings.Repeat("long note ", 50)})
+	prompt.Budget = EstimateTokens(prompt.Explainer()) + 150
+
+	rendered, err := prompt.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	if rendered.Tokens > prompt.Budget {
+		t.Errorf("Expected at most %d tokens, got %d", prompt.Budget, rendered.Tokens)
+	}
+
+	orders := rendered.Sections[1]
+	if !orders.Truncated() || orders.Items == 0 || orders.TotalItems != 100 {
+		t.Fatalf("Expected orders to be truncated, got %+v", orders)
+	}
+	if want := fmt.Sprintf("items[%d]{id,status,total}:", orders.Items); !strings.Contains(rendered.Text, want) {
+		t.Errorf("Expected %q in:\n%s", want, rendered)
+	}
+	if !strings.HasSuffix(rendered.Text, fmt.Sprintf("# showing %d of 100 items", orders.Items)) {
+		t.Errorf("Expected a truncation note, got:\n%s", rendered)
+	}
+	if !rendered.Sections[2].Omitted || strings.Contains(rendered.Text, "## notes") {
+		t.Errorf("Expected notes to be omitted, got %+v", rendered.Sections[2])
+	}
+
+	decoded, err := Decode(strings.SplitN(rendered.Text, "## orders\n", 2)[1])
+	if err != nil || len(decoded["items"].([]any)) != orders.Items {
+		t.Errorf("Expected the truncated section to decode, got %v, %v", decoded, err)
+	}
+}
+
+func TestPromptBudgetTruncatesTypedSlices(t *testing.T) {
+	type promptOrder struct {
+		ID     int     `toon:"id"`
+		Status string  `toon:"status"`
+		Total  float64 `toon:"total"`
+	}
+	orders := make([]promptOrder, 100)
+	for i := range orders {
+		orders[i] = promptOrder{ID: i + 1, Status: "shipped", Total: float64(i) + 0.5}
+	}
+
+	prompt := NewPrompt(nil).Section("orders", orders)
+	prompt.Budget = EstimateTokens(prompt.Explainer()) + 150
+
+	rendered, err := prompt.Render()
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	section := rendered.Sections[0]
+	if !section.Truncated() || section.Items == 0 || section.TotalItems != 100 {
+		t.Fatalf("Expected orders to be truncated, got %+v", section)
+	}
+	if want := fmt.Sprintf("items[%d]{id,status,total}:\n  1,shipped,0.5", section.Items); !strings.Contains(rendered.Text, want) {
+		t.Errorf("Expected %q in:\n%s", want, rendered)
+	}
+}
+
+func TestPromptExplainerFollowsConfig(t *testing.T) {
+	plain := NewPrompt(nil).Explainer()
+	if strings.Contains(plain, "&col") || strings.Contains(plain, "@{") {
+		t.Errorf("Expected no mention of disabled features:\n%s", plain)
+	}
+
+	config := DefaultConfig()
+	config.DictionaryEncoding = true
+	config.HoistConstantColumns = true
+	explainer := NewPrompt(config).Explainer()
+	if !strings.Contains(explainer, "&col") || !strings.Contains(explainer, "@{") {
+		t.Errorf("Expected enabled features to be explained:\n%s", explainer)
+	}
+}
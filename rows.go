@@ -0,0 +1,148 @@
+package gotoon
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// nullScanTypes maps the scan types drivers report to the sql.Null* types
+// used when a column may be NULL.
+var nullScanTypes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(int64(0)):    reflect.TypeOf(sql.NullInt64{}),
+	reflect.TypeOf(int32(0)):    reflect.TypeOf(sql.NullInt32{}),
+	reflect.TypeOf(int16(0)):    reflect.TypeOf(sql.NullInt16{}),
+	reflect.TypeOf(uint8(0)):    reflect.TypeOf(sql.NullByte{}),
+	reflect.TypeOf(float64(0)):  reflect.TypeOf(sql.NullFloat64{}),
+	reflect.TypeOf(false):       reflect.TypeOf(sql.NullBool{}),
+	reflect.TypeOf(""):          reflect.TypeOf(sql.NullString{}),
+	reflect.TypeOf(time.Time{}): reflect.TypeOf(sql.NullTime{}),
+}
+
+// RowsOptions configures EncodeRows. A nil *RowsOptions uses the defaults.
+type RowsOptions struct {
+	// Config configures the encoder. Nil means DefaultConfig.
+	Config *Config
+
+	// Aliases renames result columns, keyed by the column name the query
+	// returns. Dotted aliases such as "customer.name" nest the value when the
+	// table is decoded.
+	Aliases map[string]string
+}
+
+// EncodeRows encodes a query result as a single TOON table, reading rows to
+// the end and closing them. Each column is scanned into the type its driver
+// reports through ColumnTypes, using the matching sql.Null* type for nullable
+// columns, so NULL becomes an empty cell. Valuers such as sql.Null* are
+// unwrapped, text in []byte is written as a string, and binary columns
+// (BLOB, BINARY, BYTEA) or invalid UTF-8 as base64. Transformers see each
+// value at the path "*.<column>".
+func EncodeRows(rows *sql.Rows, opts *RowsOptions) (string, error) {
+	defer rows.Close()
+
+	config := DefaultConfig()
+	if opts != nil && opts.Config != nil {
+		config = opts.Config
+	}
+	encoder := NewEncoder(config)
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return "", err
+	}
+
+	columns := make([]string, len(columnTypes))
+	seen := make(map[string]bool, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = ct.Name()
+		if opts != nil && opts.Aliases[ct.Name()] != "" {
+			columns[i] = opts.Aliases[ct.Name()]
+		}
+		if seen[columns[i]] {
+			return "", fmt.Errorf("duplicate column %q; alias it in the query or in RowsOptions.Aliases", columns[i])
+		}
+		seen[columns[i]] = true
+	}
+
+	data := &FlattenedData{Columns: columns, Rows: [][]any{}}
+	for rows.Next() {
+		dest := make([]any, len(columnTypes))
+		for i, ct := range columnTypes {
+			dest[i] = scanTarget(ct)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+
+		row := make([]any, len(dest))
+		for i, target := range dest {
+			if row[i], err = columnValue(target, columnTypes[i]); err != nil {
+				return "", fmt.Errorf("column %q: %w", columns[i], err)
+			}
+			if row[i], err = encoder.transform(row[i], joinPath("*", columns[i])); err != nil {
+				return "", fmt.Errorf("column %q: %w", columns[i], err)
+			}
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return encoder.tableToToon("items", data, 0), nil
+}
+
+// scanTarget returns a pointer to scan a column into: its scan type, the
+// sql.Null* counterpart when it may be NULL, or any when the driver does not
+// report a type.
+func scanTarget(ct *sql.ColumnType) any {
+	typ := ct.ScanType()
+	if typ == nil || typ.Kind() == reflect.Interface {
+		return new(any)
+	}
+
+	if nullable, ok := ct.Nullable(); nullable || !ok {
+		if null, found := nullScanTypes[typ]; found {
+			typ = null
+		}
+	}
+	return reflect.New(typ).Interface()
+}
+
+// columnValue converts a scanned value to a value the encoder writes.
+func columnValue(target any, ct *sql.ColumnType) (any, error) {
+	value := reflect.ValueOf(target).Elem().Interface()
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch val := value.(type) {
+	case sql.RawBytes:
+		return bytesValue(val, ct), nil
+	case []byte:
+		return bytesValue(val, ct), nil
+	}
+	return value, nil
+}
+
+// bytesValue returns text as a string and binary data as base64.
+func bytesValue(b []byte, ct *sql.ColumnType) any {
+	if b == nil {
+		return nil
+	}
+
+	typ := strings.ToUpper(ct.DatabaseTypeName())
+	if strings.Contains(typ, "BLOB") || strings.Contains(typ, "BINARY") || typ == "BYTEA" || !utf8.Valid(b) {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return string(b)
+}
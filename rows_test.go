@@ -0,0 +1,192 @@
+package gotoon
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeColumn describes a column of the fake driver.
+type fakeColumn struct {
+	name     string
+	dbType   string
+	scanType reflect.Type
+	nullable bool
+}
+
+// fakeResult is the result the fake driver returns for a query.
+type fakeResult struct {
+	columns []fakeColumn
+	values  [][]driver.Value
+}
+
+var fakeQueries = map[string]fakeResult{}
+
+func init() {
+	sql.Register("gotoon-fake", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("not supported") }
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	result, ok := fakeQueries[s.query]
+	if !ok {
+		return nil, errors.New("unknown query")
+	}
+	return &fakeRows{result: result}, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	pos    int
+}
+
+func (r *fakeRows) Columns() []string {
+	names := make([]string, len(r.result.columns))
+	for i, col := range r.result.columns {
+		names[i] = col.name
+	}
+	return names
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.result.values) {
+		return io.EOF
+	}
+	copy(dest, r.result.values[r.pos])
+	r.pos++
+	return nil
+}
+
+func (r *fakeRows) ColumnTypeScanType(i int) reflect.Type {
+	return r.result.columns[i].scanType
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string {
+	return r.result.columns[i].dbType
+}
+
+func (r *fakeRows) ColumnTypeNullable(i int) (bool, bool) {
+	return r.result.columns[i].nullable, true
+}
+
+func queryFake(t *testing.T, result fakeResult) *sql.Rows {
+	t.Helper()
+
+	fakeQueries[t.Name()] = result
+	db, err := sql.Open("gotoon-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	rows, err := db.Query(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+var orderColumns = []fakeColumn{
+	{"id", "BIGINT", reflect.TypeOf(int64(0)), false},
+	{"customer_name", "VARCHAR", reflect.TypeOf(sql.RawBytes{}), true},
+	{"total", "DECIMAL", reflect.TypeOf(float64(0)), true},
+	{"paid", "BOOLEAN", reflect.TypeOf(false), true},
+	{"created_at", "TIMESTAMP", reflect.TypeOf(time.Time{}), false},
+	{"receipt", "BLOB", reflect.TypeOf([]byte{}), true},
+	{"note", "", nil, true},
+}
+
+func orderRows() [][]driver.Value {
+	created := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	return [][]driver.Value{
+		{int64(1), []byte("Alice"), 9.5, true, created, []byte{0xff, 0x00}, "gift"},
+		{int64(2), []byte("Bob, Jr"), nil, nil, created.Add(time.Hour), nil, nil},
+	}
+}
+
+func TestEncodeRows(t *testing.T) {
+	rows := queryFake(t, fakeResult{columns: orderColumns, values: orderRows()})
+
+	toon, err := EncodeRows(rows, &RowsOptions{Aliases: map[string]string{"customer_name": "customer.name"}})
+	if err != nil {
+		t.Fatalf("EncodeRows failed: %v", err)
+	}
+
+	expected := "items[2]{id,customer.name,total,paid,created_at,receipt,note}:\n" +
+		"  1,Alice,9.5,true,2024-01-02T10:00:00Z,/wA=,gift\n" +
+		"  2,Bob\\, Jr,,,2024-01-02T11:00:00Z,,"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	decoded, err := Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	second := decoded["items"].([]any)[1].(map[string]any)
+	if second["customer"].(map[string]any)["name"] != "Bob, Jr" || second["total"] != nil {
+		t.Errorf("Unexpected decoded row: %v", second)
+	}
+}
+
+func TestEncodeRowsTypedHeaders(t *testing.T) {
+	rows := queryFake(t, fakeResult{columns: orderColumns[:4], values: [][]driver.Value{
+		{int64(1), []byte("01234"), 9.5, true},
+		{int64(2), nil, int64(12), false},
+	}})
+
+	config := DefaultConfig()
+	config.TypedHeaders = true
+	toon, err := EncodeRows(rows, &RowsOptions{Config: config})
+	if err != nil {
+		t.Fatalf("EncodeRows failed: %v", err)
+	}
+
+	expected := "items[2]{id:int,customer_name:str,total:float,paid:bool}:\n  1,01234,9.5,true\n  2,,12,false"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+}
+
+func TestEncodeRowsEmpty(t *testing.T) {
+	rows := queryFake(t, fakeResult{columns: orderColumns[:2]})
+
+	toon, err := EncodeRows(rows, nil)
+	if err != nil {
+		t.Fatalf("EncodeRows failed: %v", err)
+	}
+	if toon != "items[0]{id,customer_name}:" {
+		t.Errorf("Expected the columns of an empty result, got %q", toon)
+	}
+}
+
+func TestEncodeRowsDuplicateColumns(t *testing.T) {
+	rows := queryFake(t, fakeResult{columns: []fakeColumn{orderColumns[0], orderColumns[0]}})
+
+	_, err := EncodeRows(rows, nil)
+	if err == nil || !strings.Contains(err.Error(), `duplicate column "id"`) {
+		t.Errorf("Expected a duplicate column error, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package schema
+
+import "github.com/b92c/gotoon"
+
+// Decoder type names used in gotoon.Config.TypeHints.
+const (
+	hintInt   = "int"
+	hintFloat = "float"
+	hintStr   = "str"
+	hintBool  = "bool"
+	hintTime  = "time"
+)
+
+// TypeHints returns decoder type hints for every scalar the schema declares,
+// keyed by dotted path with "*" for list items, e.g. "orders.*.total". Strings
+// with format "date-time" decode as time.Time. Values allowing several
+// non-null types get no hint.
+func (s *Schema) TypeHints() map[string]string {
+	hints := make(map[string]string)
+	s.collectHints("", hints, make(map[*Schema]bool))
+	return hints
+}
+
+// collectHints adds the hints of s at path, skipping schemas already on the
+// current branch so recursive references terminate.
+func (s *Schema) collectHints(path string, hints map[string]string, visiting map[*Schema]bool) {
+	s = s.resolve()
+	if visiting[s] {
+		return
+	}
+	visiting[s] = true
+	defer delete(visiting, s)
+
+	if hint := s.hint(); hint != "" && path != "" {
+		hints[path] = hint
+	}
+
+	for key, child := range s.Properties {
+		child.collectHints(joinPath(path, key), hints, visiting)
+	}
+	if s.Items != nil {
+		items := path
+		if items == "" {
+			// Top-level lists and tables decode under the "items" key.
+			items = "items"
+		}
+		s.Items.collectHints(joinPath(items, "*"), hints, visiting)
+	}
+}
+
+// hint returns the decoder type for a scalar schema.
+func (s *Schema) hint() string {
+	switch s.nonNullType() {
+	case TypeInteger:
+		return hintInt
+	case TypeNumber:
+		return hintFloat
+	case TypeBoolean:
+		return hintBool
+	case TypeString:
+		if s.Format == "date-time" {
+			return hintTime
+		}
+		return hintStr
+	default:
+		return ""
+	}
+}
+
+// Decode decodes toon using the schema's type hints and validates the result.
+// config may be nil; it is copied, not modified, and its own TypeHints are
+// kept where the schema declares nothing. Syntax errors are returned as
+// *gotoon.SyntaxError and mismatches as ValidationErrors, along with the data.
+func (s *Schema) Decode(toon string, config *gotoon.Config) (map[string]any, error) {
+	if config == nil {
+		config = gotoon.DefaultConfig()
+	}
+
+	hinted := *config
+	hinted.TypeHints = s.TypeHints()
+	for path, typ := range config.TypeHints {
+		if _, ok := hinted.TypeHints[path]; !ok {
+			hinted.TypeHints[path] = typ
+		}
+	}
+
+	data, err := gotoon.NewDecoder(&hinted).Decode(toon)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, s.Validate(data)
+}
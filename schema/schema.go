@@ -0,0 +1,190 @@
+// Package schema loads JSON Schemas and applies them to TOON documents: it
+// validates decoded data with path-based errors and turns the schema's types
+// into decoder type hints, so ambiguous cells such as "00123" or "10" decode
+// to the declared string or number type.
+//
+// The supported keywords are type, properties, required, additionalProperties,
+// items, enum, const, format, minimum, maximum, minLength, maxLength, pattern,
+// minItems, maxItems and local "$ref"s into "$defs" or "definitions".
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// JSON Schema type names.
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// Schema is a JSON Schema node.
+type Schema struct {
+	Type                 Types              `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+
+	root    *Schema
+	pattern *regexp.Regexp
+}
+
+// Types holds the "type" keyword, which may be a single name or a list.
+type Types []string
+
+// UnmarshalJSON accepts both "string" and ["string", "null"].
+func (t *Types) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = Types{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("schema: type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// MarshalJSON writes a single type as a string.
+func (t Types) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// Has reports whether name is one of the types.
+func (t Types) Has(name string) bool {
+	for _, typ := range t {
+		if typ == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a JSON Schema document.
+func Parse(data []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+
+	if err := s.compile(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Load reads and parses the JSON Schema file at path.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// compile links every node to the root schema, compiles patterns and checks
+// that references can be resolved.
+func (s *Schema) compile(root *Schema) error {
+	if s.root != nil {
+		return nil
+	}
+	s.root = root
+
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("schema: invalid pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+
+	if s.Ref != "" && root.lookup(s.Ref) == nil {
+		return fmt.Errorf("schema: unresolved reference %q", s.Ref)
+	}
+
+	for _, children := range []map[string]*Schema{s.Properties, s.Defs, s.Definitions} {
+		for _, child := range children {
+			if err := child.compile(root); err != nil {
+				return err
+			}
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(root)
+	}
+	return nil
+}
+
+// resolve follows "$ref" to the schema it points to.
+func (s *Schema) resolve() *Schema {
+	for seen := 0; s.Ref != "" && s.root != nil && seen < 32; seen++ {
+		target := s.root.lookup(s.Ref)
+		if target == nil {
+			break
+		}
+		s = target
+	}
+	return s
+}
+
+// lookup finds a local reference such as "#/$defs/address".
+func (s *Schema) lookup(ref string) *Schema {
+	if ref == "#" {
+		return s
+	}
+
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if ok {
+		return s.Defs[name]
+	}
+	if name, ok := strings.CutPrefix(ref, "#/definitions/"); ok {
+		return s.Definitions[name]
+	}
+	return nil
+}
+
+// nonNullType returns the only type other than "null", or "" if there isn't
+// exactly one.
+func (s *Schema) nonNullType() string {
+	typ := ""
+	for _, t := range s.Type {
+		if t == TypeNull {
+			continue
+		}
+		if typ != "" {
+			return ""
+		}
+		typ = t
+	}
+	return typ
+}
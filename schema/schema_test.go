@@ -0,0 +1,208 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/b92c/gotoon"
+)
+
+const ordersSchema = `{
+  "type": "object",
+  "required": ["orders", "store"],
+  "properties": {
+    "store": {
+      "type": "object",
+      "properties": {
+        "zip": {"type": "string"},
+        "opened": {"type": "string", "format": "date-time"}
+      }
+    },
+    "orders": {
+      "type": "array",
+      "minItems": 1,
+      "items": {"$ref": "#/$defs/order"}
+    }
+  },
+  "$defs": {
+    "order": {
+      "type": "object",
+      "required": ["id", "status", "total"],
+      "additionalProperties": false,
+      "properties": {
+        "id": {"type": "integer"},
+        "sku": {"type": "string", "pattern": "^[0-9]+$"},
+        "status": {"enum": ["open", "closed"]},
+        "total": {"type": "number", "minimum": 0},
+        "note": {"type": ["string", "null"]}
+      }
+    }
+  }
+}`
+
+func mustParse(t *testing.T) *Schema {
+	t.Helper()
+	s, err := Parse([]byte(ordersSchema))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return s
+}
+
+func TestTypeHints(t *testing.T) {
+	expected := map[string]string{
+		"store.zip":      "str",
+		"store.opened":   "time",
+		"orders.*.id":    "int",
+		"orders.*.sku":   "str",
+		"orders.*.total": "float",
+		"orders.*.note":  "str",
+	}
+
+	if hints := mustParse(t).TypeHints(); !reflect.DeepEqual(hints, expected) {
+		t.Errorf("Unexpected hints.\nExpected: %v\nGot:      %v", expected, hints)
+	}
+}
+
+func TestDecodeCoercesAmbiguousValues(t *testing.T) {
+	toon := `orders:
+  items[2]{id,sku,status,total}:
+    1,00123,open,10
+    2,456,closed,12.5
+store:
+  opened: 2024-01-02T10\:00\:00Z
+  zip: 01234`
+
+	data, err := mustParse(t).Decode(toon, nil)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	first := data["orders"].([]any)[0].(map[string]any)
+	if first["sku"] != "00123" {
+		t.Errorf("Expected sku to stay a string, got %T %v", first["sku"], first["sku"])
+	}
+	if total, ok := first["total"].(float64); !ok || total != 10 {
+		t.Errorf("Expected total to decode as float64, got %T %v", first["total"], first["total"])
+	}
+
+	store := data["store"].(map[string]any)
+	if store["zip"] != "01234" {
+		t.Errorf("Expected zip to stay a string, got %T %v", store["zip"], store["zip"])
+	}
+	if opened, ok := store["opened"].(time.Time); !ok || opened.Year() != 2024 {
+		t.Errorf("Expected opened to decode as time.Time, got %T %v", store["opened"], store["opened"])
+	}
+
+	plain, _ := gotoon.Decode(toon)
+	if plain["store"].(map[string]any)["zip"] != 1234 {
+		t.Errorf("Expected plain decode to guess an int, got %v", plain["store"])
+	}
+}
+
+func TestValidateReportsPaths(t *testing.T) {
+	data := map[string]any{
+		"orders": []any{
+			map[string]any{"id": 1, "status": "open", "total": 3.5},
+			map[string]any{"id": 2.5, "status": "lost", "total": -1, "extra": true},
+			map[string]any{"id": 3, "sku": "x1", "total": 1},
+		},
+	}
+
+	err := mustParse(t).Validate(data)
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got: %v", err)
+	}
+
+	expected := []string{
+		"store: required property is missing",
+		"orders.1.extra: property is not allowed",
+		"orders.1.id: expected integer, got number",
+		"orders.1.status: value lost is not one of [open closed]",
+		"orders.1.total: value -1 is less than minimum 0",
+		"orders.2.status: required property is missing",
+		`orders.2.sku: value "x1" does not match pattern "^[0-9]+$"`,
+	}
+
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Error()
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected errors.\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+func TestValidateAcceptsValidData(t *testing.T) {
+	data := map[string]any{
+		"store":  map[string]any{"zip": "01234"},
+		"orders": []any{map[string]any{"id": 1, "status": "open", "total": 0, "note": nil}},
+	}
+
+	if err := mustParse(t).Validate(data); err != nil {
+		t.Errorf("Expected data to be valid, got: %v", err)
+	}
+}
+
+func TestValidateChecksTimes(t *testing.T) {
+	s, err := Parse([]byte(`{
+  "type": "object",
+  "properties": {
+    "at": {"type": "string", "format": "date-time", "pattern": "^2024-"},
+    "day": {"type": "string", "format": "date"}
+  }
+}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := s.Validate(map[string]any{"at": day, "day": day}); err != nil {
+		t.Errorf("Expected times to be valid, got: %v", err)
+	}
+
+	err = s.Validate(map[string]any{"at": time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)})
+	expected := `at: value "2023-12-31T23:00:00Z" does not match pattern "^2024-"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got: %v", expected, err)
+	}
+}
+
+func TestParseRejectsUnresolvedReference(t *testing.T) {
+	if _, err := Parse([]byte(`{"items": {"$ref": "#/$defs/missing"}}`)); err == nil {
+		t.Error("Expected an error for an unresolved reference")
+	}
+}
+
+func TestInferredSchemaValidatesSamples(t *testing.T) {
+	samples := []any{
+		map[string]any{"id": 1, "status": "open", "note": nil},
+		map[string]any{"id": 2, "status": "open", "note": "x"},
+		map[string]any{"id": 3, "status": "closed"},
+		map[string]any{"id": 4, "status": "closed"},
+	}
+
+	data, err := gotoon.InferSchema(samples...).JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema failed: %v", err)
+	}
+
+	s, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	for _, sample := range samples {
+		if err := s.Validate(sample); err != nil {
+			t.Errorf("Expected sample to be valid, got: %v", err)
+		}
+	}
+
+	if err := s.Validate(map[string]any{"id": "x", "status": "lost"}); err == nil {
+		t.Error("Expected mismatching data to be invalid")
+	}
+}
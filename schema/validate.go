@@ -0,0 +1,277 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError describes a value that doesn't match its schema. Path is the
+// dotted path of the value, with list items addressed by index, e.g.
+// "orders.1.total"; it is empty for the document itself.
+type ValidationError struct {
+	Path string
+	Msg  string
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return path + ": " + e.Msg
+}
+
+// ValidationErrors lists every problem found in a document.
+type ValidationErrors []*ValidationError
+
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks v against the schema and returns ValidationErrors listing
+// every mismatch, or nil. v is decoded data such as the result of
+// gotoon.Decode: maps, []any, strings, numbers, booleans, time.Time and nil.
+// A time.Time is checked as a string holding its RFC 3339 text.
+func (s *Schema) Validate(v any) error {
+	var errs ValidationErrors
+	s.validate(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validate appends the mismatches of v at path to errs.
+func (s *Schema) validate(v any, path string, errs *ValidationErrors) {
+	s = s.resolve()
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, &ValidationError{Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(v) {
+		fail("expected %s, got %s", strings.Join(s.Type, " or "), typeName(v))
+		return
+	}
+
+	if len(s.Enum) > 0 && !containsValue(s.Enum, v) {
+		fail("value %v is not one of %v", v, s.Enum)
+	}
+	if s.Const != nil && !equalValues(s.Const, v) {
+		fail("value %v is not %v", v, s.Const)
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		s.validateObject(val, path, errs)
+	case []any:
+		s.validateArray(val, path, errs)
+	case string:
+		s.validateString(val, fail)
+	case time.Time:
+		// Times are checked by their RFC 3339 text, or by their calendar
+		// date against a "date" format.
+		text := val.Format(time.RFC3339)
+		if s.Format == "date" {
+			text = val.Format(time.DateOnly)
+		}
+		s.validateString(text, fail)
+	}
+
+	if n, ok := toFloat(v); ok {
+		if s.Minimum != nil && n < *s.Minimum {
+			fail("value %v is less than minimum %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			fail("value %v is greater than maximum %v", v, *s.Maximum)
+		}
+	}
+}
+
+// validateObject checks required, declared and additional properties.
+func (s *Schema) validateObject(obj map[string]any, path string, errs *ValidationErrors) {
+	for _, key := range s.Required {
+		if _, ok := obj[key]; !ok {
+			*errs = append(*errs, &ValidationError{Path: joinPath(path, key), Msg: "required property is missing"})
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child, ok := s.Properties[key]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, &ValidationError{Path: joinPath(path, key), Msg: "property is not allowed"})
+			}
+			continue
+		}
+		child.validate(obj[key], joinPath(path, key), errs)
+	}
+}
+
+// validateArray checks the item count and every item.
+func (s *Schema) validateArray(arr []any, path string, errs *ValidationErrors) {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		*errs = append(*errs, &ValidationError{Path: path, Msg: fmt.Sprintf("expected at least %d items, got %d", *s.MinItems, len(arr))})
+	}
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		*errs = append(*errs, &ValidationError{Path: path, Msg: fmt.Sprintf("expected at most %d items, got %d", *s.MaxItems, len(arr))})
+	}
+
+	if s.Items == nil {
+		return
+	}
+	for i, item := range arr {
+		s.Items.validate(item, joinPath(path, strconv.Itoa(i)), errs)
+	}
+}
+
+// validateString checks length, pattern and format.
+func (s *Schema) validateString(str string, fail func(string, ...any)) {
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		fail("expected at least %d characters, got %d", *s.MinLength, length)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		fail("expected at most %d characters, got %d", *s.MaxLength, length)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		fail("value %q does not match pattern %q", str, s.Pattern)
+	}
+
+	switch s.Format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			fail("value %q is not a date-time", str)
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, str); err != nil {
+			fail("value %q is not a date", str)
+		}
+	}
+}
+
+// matchesType reports whether v has one of the schema's types. time.Time counts
+// as a string, and integral floats count as integers.
+func (s *Schema) matchesType(v any) bool {
+	for _, typ := range s.Type {
+		switch typ {
+		case TypeNull:
+			if v == nil {
+				return true
+			}
+		case TypeObject:
+			if _, ok := v.(map[string]any); ok {
+				return true
+			}
+		case TypeArray:
+			if _, ok := v.([]any); ok {
+				return true
+			}
+		case TypeString:
+			switch v.(type) {
+			case string, time.Time:
+				return true
+			}
+		case TypeBoolean:
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case TypeNumber:
+			if _, ok := toFloat(v); ok {
+				return true
+			}
+		case TypeInteger:
+			if n, ok := toFloat(v); ok && n == math.Trunc(n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeName returns the JSON Schema type name of v.
+func typeName(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return TypeNull
+	case map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	case string, time.Time:
+		return TypeString
+	case bool:
+		return TypeBoolean
+	default:
+		if n, ok := toFloat(val); ok {
+			if n == math.Trunc(n) {
+				return TypeInteger
+			}
+			return TypeNumber
+		}
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// toFloat converts any Go number to float64.
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// equalValues compares values, treating numbers of different Go types as equal
+// when they have the same value.
+func equalValues(a, b any) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	if t, ok := b.(time.Time); ok {
+		b = t.Format(time.RFC3339)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// containsValue reports whether values contains v.
+func containsValue(values []any, v any) bool {
+	for _, candidate := range values {
+		if equalValues(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath appends key to a dotted path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
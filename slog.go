@@ -0,0 +1,300 @@
+package gotoon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogHandlerOptions configures a LogHandler. A nil *LogHandlerOptions uses the
+// defaults.
+type LogHandlerOptions struct {
+	// Level is the minimum level logged. Nil means slog.LevelInfo.
+	Level slog.Leveler
+
+	// AddSource adds a "source" key with the file and line of the log call.
+	AddSource bool
+
+	// ReplaceAttr rewrites or drops attributes before they are written, as in
+	// slog.HandlerOptions. It is also called for the built-in time, level,
+	// msg and source keys with nil groups.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+
+	// Config configures the encoder. Nil means DefaultConfig.
+	Config *Config
+
+	// BatchSize buffers records and writes every BatchSize of them as one
+	// table whose columns are shared by all records. Zero writes each record
+	// as soon as it is handled.
+	BatchSize int
+}
+
+// LogHandler is a slog.Handler that writes records as TOON. Built-in keys and
+// attributes become keys, and groups become nested objects. Each record is
+// written as a "- " list item, so the output is a TOON list. In batch mode
+// records are written as tables instead, each a document of its own, with
+// groups flattened into dotted columns such as "req.method"; call Flush to
+// write a partial batch before exiting. Transformers see each record as a
+// list item, at the path "*", e.g. "*.req.body".
+type LogHandler struct {
+	opts    LogHandlerOptions
+	encoder *Encoder
+	sink    *logSink
+
+	attrs  map[string]any
+	groups []string
+}
+
+// logSink is the writer and pending batch shared by a handler and the
+// handlers derived from it.
+type logSink struct {
+	mu    sync.Mutex
+	w     io.Writer
+	batch []any
+}
+
+// NewLogHandler creates a LogHandler writing to w.
+func NewLogHandler(w io.Writer, opts *LogHandlerOptions) *LogHandler {
+	h := &LogHandler{sink: &logSink{w: w}, attrs: map[string]any{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.encoder = NewEncoder(h.opts.Config)
+	return h
+}
+
+// Enabled reports whether records at level are logged.
+func (h *LogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle writes r, or adds it to the pending batch.
+func (h *LogHandler) Handle(_ context.Context, r slog.Record) error {
+	record := deepCopy(h.attrs).(map[string]any)
+
+	target := record
+	for _, group := range h.groups {
+		target = logGroup(target, group)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.addAttr(target, h.groups, a)
+		return true
+	})
+	pruneEmptyGroups(record)
+
+	if !r.Time.IsZero() {
+		h.addBuiltin(record, slog.Time(slog.TimeKey, r.Time))
+	}
+	h.addBuiltin(record, slog.String(slog.LevelKey, r.Level.String()))
+	h.addBuiltin(record, slog.String(slog.MessageKey, r.Message))
+	if h.opts.AddSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		h.addBuiltin(record, slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", frame.File, frame.Line)))
+	}
+
+	transformed, err := h.encoder.transform(record, "*")
+	if err != nil {
+		return err
+	}
+
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+
+	if h.opts.BatchSize <= 0 {
+		_, err = io.WriteString(h.sink.w, h.listItem(transformed))
+		return err
+	}
+
+	h.sink.batch = append(h.sink.batch, transformed)
+	if len(h.sink.batch) < h.opts.BatchSize {
+		return nil
+	}
+	return h.writeBatch()
+}
+
+// WithAttrs returns a handler that adds attrs to every record, inside the
+// current group.
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	clone := h.clone()
+	target := clone.attrs
+	for _, group := range clone.groups {
+		target = logGroup(target, group)
+	}
+	for _, a := range attrs {
+		clone.addAttr(target, clone.groups, a)
+	}
+	pruneEmptyGroups(clone.attrs)
+	return clone
+}
+
+// WithGroup returns a handler that nests later attributes under name.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	clone := h.clone()
+	clone.groups = append(clone.groups[:len(clone.groups):len(clone.groups)], name)
+	return clone
+}
+
+// Flush writes the pending batch, if any. It does nothing outside batch mode.
+func (h *LogHandler) Flush() error {
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+	return h.writeBatch()
+}
+
+// clone copies h, sharing its sink.
+func (h *LogHandler) clone() *LogHandler {
+	clone := *h
+	clone.attrs = deepCopy(h.attrs).(map[string]any)
+	return &clone
+}
+
+// writeBatch writes the pending records as one table. The caller holds the
+// sink lock.
+func (h *LogHandler) writeBatch() error {
+	if len(h.sink.batch) == 0 {
+		return nil
+	}
+
+	flattened := h.encoder.flattener.Flatten(h.sink.batch)
+	h.sink.batch = nil
+	_, err := io.WriteString(h.sink.w, h.encoder.tableToToon("items", flattened, 0)+"\n")
+	return err
+}
+
+// listItem renders record as a "- " list item.
+func (h *LogHandler) listItem(record any) string {
+	lines := strings.Split(h.encoder.valueToToon(record, 0, ""), "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = "- " + lines[i]
+		} else {
+			lines[i] = "  " + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// addBuiltin adds one of the built-in keys, applying ReplaceAttr.
+func (h *LogHandler) addBuiltin(record map[string]any, a slog.Attr) {
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+	}
+	if a.Key != "" {
+		record[a.Key] = logValue(a.Value)
+	}
+}
+
+// addAttr adds a to target, where groups is the group path of target.
+// Groups with a key become nested objects; groups without one are inlined.
+func (h *LogHandler) addAttr(target map[string]any, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			target = logGroup(target, a.Key)
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, member := range a.Value.Group() {
+			h.addAttr(target, groups, member)
+		}
+		return
+	}
+
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) || a.Key == "" {
+		return
+	}
+	target[a.Key] = logValue(a.Value)
+}
+
+// logGroup returns the nested object for group in target, creating it.
+func logGroup(target map[string]any, group string) map[string]any {
+	if nested, ok := target[group].(map[string]any); ok {
+		return nested
+	}
+	nested := map[string]any{}
+	target[group] = nested
+	return nested
+}
+
+// pruneEmptyGroups removes groups that received no attributes.
+func pruneEmptyGroups(m map[string]any) {
+	for key, value := range m {
+		if nested, ok := value.(map[string]any); ok {
+			pruneEmptyGroups(nested)
+			if len(nested) == 0 {
+				delete(m, key)
+			}
+		}
+	}
+}
+
+// logValue converts a slog value to a value the encoder writes. Durations
+// and errors become strings, and other values are normalized through JSON.
+func logValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time()
+	case slog.KindGroup:
+		group := map[string]any{}
+		for _, a := range v.Group() {
+			group[a.Key] = logValue(a.Value.Resolve())
+		}
+		return group
+	}
+
+	value := v.Any()
+	switch val := value.(type) {
+	case nil, string, time.Time, map[string]any, []any:
+		return val
+	case error:
+		return val.Error()
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var normalized any
+	if err := decoder.Decode(&normalized); err != nil {
+		return fmt.Sprint(value)
+	}
+	return normalizeJSON(normalized)
+}
@@ -0,0 +1,108 @@
+package gotoon
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+)
+
+// withoutTime drops the time key so output is stable.
+func withoutTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && groups == nil {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(&buf, &LogHandlerOptions{ReplaceAttr: withoutTime}))
+
+	base := logger.With("app", "api")
+	base.WithGroup("req").Info("started", "method", "GET", slog.Int("status", 200))
+	base.Warn("slow, retrying", "ms", 12.5, "err", errors.New("timeout"))
+	base.WithGroup("unused").Error("failed")
+	base.Debug("hidden")
+
+	expected := "- app: api\n  level: INFO\n  msg: started\n  req:\n    method: GET\n    status: 200\n" +
+		"- app: api\n  err: timeout\n  level: WARN\n  ms: 12.5\n  msg: slow\\, retrying\n" +
+		"- app: api\n  level: ERROR\n  msg: failed\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	decoded, err := Decode(buf.String())
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if items := decoded["items"].([]any); len(items) != 3 {
+		t.Errorf("Expected 3 records, got %v", decoded)
+	}
+}
+
+func TestLogHandlerGroupsAndValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(&buf, &LogHandlerOptions{ReplaceAttr: withoutTime, Level: slog.LevelDebug}))
+
+	type user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	logger.WithGroup("req").With("id", 7).Debug("done",
+		slog.Group("user", "id", 1),
+		slog.Group("", "inline", true),
+		"payload", user{ID: 2, Name: "Bob"},
+		"tags", []string{"a", "b"})
+
+	expected := "- level: DEBUG\n  msg: done\n  req:\n    id: 7\n    inline: true\n" +
+		"    payload:\n      id: 2\n      name: Bob\n    tags:\n      - a\n      - b\n    user:\n      id: 1\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected output.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestLogHandlerBatch(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewLogHandler(&buf, &LogHandlerOptions{ReplaceAttr: withoutTime, BatchSize: 2})
+	logger := slog.New(handler)
+
+	logger.Info("started", "user", "alice")
+	logger.With("req", "r1").Info("query", slog.Group("db", "ms", 3))
+	logger.Error("failed", "user", "bob")
+
+	expected := "items[2]{level,msg,user,db.ms,req}:\n  INFO,started,alice,,\n  INFO,query,,3,r1\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected first batch.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+
+	if err := handler.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if rest := strings.TrimPrefix(buf.String(), expected); rest != "items[1]{level,msg,user}:\n  ERROR,failed,bob\n" {
+		t.Errorf("Unexpected flushed batch:\n%s", rest)
+	}
+}
+
+func TestLogHandlerConformance(t *testing.T) {
+	var buf bytes.Buffer
+	results := func() []map[string]any {
+		decoded, err := Decode(buf.String())
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+
+		var records []map[string]any
+		items, _ := decoded["items"].([]any)
+		for _, item := range items {
+			records = append(records, item.(map[string]any))
+		}
+		return records
+	}
+
+	if err := slogtest.TestHandler(NewLogHandler(&buf, nil), results); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,112 @@
+package gotoon
+
+import (
+	"errors"
+	"testing"
+)
+
+func strictDecoder() *Decoder {
+	config := DefaultConfig()
+	config.Strict = true
+	return NewDecoder(config)
+}
+
+func TestStrictDecodeAcceptsEncoderOutput(t *testing.T) {
+	config := DefaultConfig()
+	config.DictionaryEncoding = true
+	config.HoistConstantColumns = true
+	config.NormalizeObjects = true
+	config.SparseTables = true
+
+	customer := map[string]any{"id": "c1", "name": "Alice"}
+	data := map[string]any{
+		"count": 3,
+		"tags":  []any{"a", "b"},
+		"orders": []any{
+			map[string]any{"id": 1, "status": "open", "customer": customer, "meta": []any{1, 2}},
+			map[string]any{"id": 2, "status": "open", "customer": customer},
+			map[string]any{"id": 3, "status": "closed", "customer": customer, "meta": []any{}},
+		},
+		"mixed": []any{1, map[string]any{"a": "x, y"}, []any{"z"}},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	if _, err := strictDecoder().Decode(toon); err != nil {
+		t.Errorf("Strict decode rejected encoder output: %v\n%s", err, toon)
+	}
+}
+
+func TestStrictDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		toon   string
+		line   int
+		column int
+	}{
+		{
+			name:   "missing rows",
+			toon:   "users:\n  items[3]{id,name}:\n    1,Alice\n    2,Bob",
+			line:   2,
+			column: 3,
+		},
+		{
+			name:   "too many cells",
+			toon:   "items[2]{id,name}:\n  1,Alice\n  2,Bob,extra",
+			line:   3,
+			column: 3,
+		},
+		{
+			name:   "unexpected indentation",
+			toon:   "name: Alice\n    age: 30",
+			line:   2,
+			column: 5,
+		},
+		{
+			name:   "unrecognized line",
+			toon:   "user:\n  name: Alice\n  just some text",
+			line:   3,
+			column: 3,
+		},
+		{
+			name:   "invalid json cell",
+			toon:   "items[2]{id,tags:json}:\n  1,[\"a\"]\n  2,[oops",
+			line:   3,
+			column: 3,
+		},
+		{
+			name:   "row not indented",
+			toon:   "items[2]{id}:\n  1\ncount: 2",
+			line:   3,
+			column: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := strictDecoder().Decode(tt.toon)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected *SyntaxError, got: %v", err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+				t.Errorf("Expected error at %d:%d, got: %v", tt.line, tt.column, syntaxErr)
+			}
+		})
+	}
+}
+
+func TestLenientDecodeIgnoresErrors(t *testing.T) {
+	decoded, err := Decode("items[3]{id,name}:\n  1,Alice\n  2,Bob")
+	if err != nil {
+		t.Fatalf("Expected lenient decode to succeed, got: %v", err)
+	}
+
+	if items := decoded["items"].([]any); len(items) != 2 {
+		t.Errorf("Expected 2 items, got: %+v", items)
+	}
+}
@@ -0,0 +1,163 @@
+package gotoon
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Transformer rewrites a value before it is encoded and returns the value to
+// encode in its place. See Config.TypeTransformers and Config.PathTransformers.
+type Transformer func(value any) any
+
+// RoundFloat returns a Transformer that rounds floats to places decimal
+// places. Other values are returned as they are.
+func RoundFloat(places int) Transformer {
+	scale := math.Pow10(places)
+	return func(value any) any {
+		switch val := value.(type) {
+		case float64:
+			return math.Round(val*scale) / scale
+		case float32:
+			return float32(math.Round(float64(val)*scale) / scale)
+		}
+		return value
+	}
+}
+
+// TruncateString returns a Transformer that cuts strings longer than max
+// characters to max and adds "...", as TruncateStrings does. Other values are
+// returned as they are.
+func TruncateString(max int) Transformer {
+	return func(value any) any {
+		s, ok := value.(string)
+		if !ok || utf8.RuneCountInString(s) <= max {
+			return value
+		}
+		return string([]rune(s)[:max]) + "..."
+	}
+}
+
+// FormatTime returns a Transformer that formats time.Time values and ISO date
+// strings with layout, as DateFormat does. Other values are returned as they
+// are.
+func FormatTime(layout string) Transformer {
+	return func(value any) any {
+		switch val := value.(type) {
+		case time.Time:
+			return val.Format(layout)
+		case string:
+			if t, ok := parseISODate(val); ok {
+				return t.Format(layout)
+			}
+		}
+		return value
+	}
+}
+
+// transform converts value with marshalValue, applying type transformers,
+// and then applies the path transformers. path is the dotted path of value
+// with "*" for list items.
+func (e *Encoder) transform(value any, path string) (any, error) {
+	value, err := e.marshalValue(value, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.config.PathTransformers) == 0 {
+		return value, nil
+	}
+	return e.transformPaths(value, path), nil
+}
+
+// transformPaths applies the transformer matching path to value, then to its
+// keys and items. Maps and slices are copied rather than modified.
+func (e *Encoder) transformPaths(value any, path string) any {
+	if transform := e.pathTransformer(path); transform != nil {
+		value = transform(value)
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for key, item := range val {
+			out[key] = e.transformPaths(item, joinPath(path, key))
+		}
+		return out
+
+	case []any:
+		out := make([]any, len(val))
+		itemPath := joinPath(path, "*")
+		for i, item := range val {
+			out[i] = e.transformPaths(item, itemPath)
+		}
+		return out
+	}
+	return value
+}
+
+// pathTransformer returns the transformer whose pattern matches path, or nil.
+func (e *Encoder) pathTransformer(path string) Transformer {
+	transform, _ := lookupPath(e.config.PathTransformers, path)
+	return transform
+}
+
+// lookupPath returns the entry of patterns matching path. An exact pattern
+// wins; otherwise the one with the fewest "**" segments, then the fewest "*"
+// segments, then the first in sort order.
+func lookupPath[T any](patterns map[string]T, path string) (T, bool) {
+	if value, ok := patterns[path]; ok {
+		return value, true
+	}
+
+	segments := strings.Split(path, ".")
+	var best string
+	bestGlobs, bestWildcards := -1, -1
+	for pattern := range patterns {
+		patternSegments := strings.Split(pattern, ".")
+		if !matchSegments(patternSegments, segments) {
+			continue
+		}
+		globs, wildcards := 0, 0
+		for _, segment := range patternSegments {
+			switch segment {
+			case "**":
+				globs++
+			case "*":
+				wildcards++
+			}
+		}
+		if bestGlobs < 0 || globs < bestGlobs || globs == bestGlobs && (wildcards < bestWildcards || wildcards == bestWildcards && pattern < best) {
+			best, bestGlobs, bestWildcards = pattern, globs, wildcards
+		}
+	}
+	if bestGlobs < 0 {
+		var zero T
+		return zero, false
+	}
+	return patterns[best], true
+}
+
+// matchSegments reports whether a dotted pattern, split into segments,
+// matches a path. A "*" segment matches any single key or list item, and a
+// "**" segment any number of them, including none.
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		switch segment := pattern[0]; {
+		case segment == "**":
+			for skip := 0; skip <= len(path); skip++ {
+				if matchSegments(pattern[1:], path[skip:]) {
+					return true
+				}
+			}
+			return false
+		case len(path) == 0:
+			return false
+		case segment == "*" || segment == path[0]:
+			pattern, path = pattern[1:], path[1:]
+		default:
+			return false
+		}
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,202 @@
+package gotoon
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathTransformers(t *testing.T) {
+	config := DefaultConfig()
+	config.PathTransformers = map[string]Transformer{
+		"orders.*.total":      RoundFloat(2),
+		"orders.*.note":       TruncateString(5),
+		"orders.*.created_at": FormatTime("2006-01-02"),
+	}
+
+	created := time.Date(2024, 1, 2, 10, 30, 0, 0, time.UTC)
+	data := map[string]any{
+		"orders": []any{
+			map[string]any{"total": 12.3456, "note": "leave at door", "created_at": created},
+			map[string]any{"total": 7.006, "note": "ok", "created_at": "2024-01-03T08:00:00Z"},
+		},
+		"total": 19.3506,
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "orders:\n  items[2]{created_at,note,total}:\n" +
+		"    2024-01-02,leave...,12.35\n" +
+		"    2024-01-03,ok,7.01\n" +
+		"total: 19.3506"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	first := data["orders"].([]any)[0].(map[string]any)
+	if first["total"] != 12.3456 {
+		t.Errorf("Expected the input to be left unchanged, got %v", first)
+	}
+}
+
+func TestPathTransformerWildcards(t *testing.T) {
+	config := DefaultConfig()
+	config.PathTransformers = map[string]Transformer{
+		"*.description":        TruncateString(4),
+		"shop.*.description":   TruncateString(6),
+		"shop.owner.name":      TruncateString(2),
+		"*.*.name":             TruncateString(3),
+		"shop.items.*.details": func(any) any { return "hidden" },
+	}
+
+	data := map[string]any{
+		"user": map[string]any{"description": "collector", "name": "Alexandra"},
+		"shop": map[string]any{
+			"owner": map[string]any{"name": "Bernadette", "description": "founder"},
+			"items": []any{
+				map[string]any{"details": map[string]any{"size": "XL"}},
+				map[string]any{"details": "n/a"},
+			},
+			"description": "corner store",
+		},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "shop:\n  description: corn...\n  items:\n    items[2]{details}:\n      hidden\n      hidden\n" +
+		"  owner:\n    description: founde...\n    name: Be...\n" +
+		"user:\n  description: coll...\n  name: Alexandra"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+}
+
+func TestPathTransformerDeepWildcard(t *testing.T) {
+	data := map[string]any{
+		"user": map[string]any{"description": "collector"},
+		"orders": []any{
+			map[string]any{"id": 1, "description": "gift wrapped"},
+			map[string]any{"id": 2, "description": "express"},
+		},
+	}
+
+	config := DefaultConfig()
+	config.PathTransformers = map[string]Transformer{"*.description": TruncateString(3)}
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	expected := "orders:\n  items[2]{description,id}:\n    gift wrapped,1\n    express,2\nuser:\n  description: col..."
+	if toon != expected {
+		t.Errorf("Expected \"*\" to match one segment only.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	config.PathTransformers = map[string]Transformer{
+		"**.description":   TruncateString(3),
+		"user.description": TruncateString(5),
+	}
+	toon, err = NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	expected = "orders:\n  items[2]{description,id}:\n    gif...,1\n    exp...,2\nuser:\n  description: colle..."
+	if toon != expected {
+		t.Errorf("Expected \"**\" to match any depth.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+}
+
+func TestTypeTransformers(t *testing.T) {
+	config := DefaultConfig()
+	config.TypeTransformers = map[reflect.Type]Transformer{
+		reflect.TypeFor[time.Time](): FormatTime("2006-01-02"),
+		reflect.TypeFor[money]():     func(v any) any { return float64(v.(money).Cents) / 100 },
+	}
+	config.PathTransformers = map[string]Transformer{"*.description": TruncateString(8)}
+
+	type product struct {
+		Description string    `json:"description"`
+		Price       money     `json:"price"`
+		Added       time.Time `json:"added"`
+	}
+	products := []product{
+		{"Wireless mouse", money{2500, "USD"}, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)},
+		{"Cable", money{799, "USD"}, time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)},
+	}
+
+	toon, err := NewEncoder(config).Encode(products)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := "items[2]{added,description,price}:\n  2024-05-01,Wireless...,25\n  2024-05-02,Cable,7.99"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+}
+
+func TestTransformersInStreams(t *testing.T) {
+	config := DefaultConfig()
+	config.PathTransformers = map[string]Transformer{"*.ms": RoundFloat(1)}
+
+	var out strings.Builder
+	input := "{\"id\":1,\"ms\":3.14159}\n{\"id\":2,\"ms\":2.71828}\n"
+	if err := FromNDJSON(&out, strings.NewReader(input), &NDJSONOptions{Config: config}); err != nil {
+		t.Fatalf("FromNDJSON failed: %v", err)
+	}
+	if expected := "items[2]{id,ms}:\n  1,3.1\n  2,2.7\n"; out.String() != expected {
+		t.Errorf("Unexpected NDJSON table.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+
+	config.TypeTransformers = map[reflect.Type]Transformer{reflect.TypeFor[time.Time](): FormatTime("2006-01-02")}
+	created := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	rows := queryFake(t, fakeResult{columns: orderColumns[4:5], values: [][]driver.Value{{created}, {created}}})
+	toon, err := EncodeRows(rows, &RowsOptions{Config: config})
+	if err != nil {
+		t.Fatalf("EncodeRows failed: %v", err)
+	}
+	if expected := "items[2]{created_at}:\n  2024-01-02\n  2024-01-02"; toon != expected {
+		t.Errorf("Unexpected rows table.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(&buf, &LogHandlerOptions{ReplaceAttr: withoutTime, Config: config}))
+	logger.Info("query", "ms", 12.345)
+	if expected := "- level: INFO\n  ms: 12.3\n  msg: query\n"; buf.String() != expected {
+		t.Errorf("Unexpected log record.\nExpected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
+
+func TestTransformerHelpers(t *testing.T) {
+	tests := []struct {
+		name        string
+		transformer Transformer
+		input       any
+		expected    any
+	}{
+		{"round float64", RoundFloat(2), 1.005001, 1.01},
+		{"round float32", RoundFloat(1), float32(2.25), float32(2.3)},
+		{"round ignores ints", RoundFloat(2), 3, 3},
+		{"truncate runes", TruncateString(3), "héllo", "hél..."},
+		{"truncate short", TruncateString(10), "short", "short"},
+		{"format ISO string", FormatTime("Jan 2"), "2024-03-05", "Mar 5"},
+		{"format ignores text", FormatTime("Jan 2"), "soon", "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.transformer(tt.input); got != tt.expected {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, got, got)
+			}
+		})
+	}
+}
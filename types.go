@@ -0,0 +1,128 @@
+package gotoon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column types written in typed table headers, e.g. "price:float".
+const (
+	intColumnType   = "int"
+	floatColumnType = "float"
+	strColumnType   = "str"
+	boolColumnType  = "bool"
+	timeColumnType  = "time"
+)
+
+// columnType returns the type shared by all non-nil values in the column, or ""
+// when the values differ or the column is empty. Columns mixing integers and
+// floats are typed as float.
+func columnType(rows [][]any, col int) string {
+	typ := ""
+	for _, row := range rows {
+		if col >= len(row) || row[col] == nil {
+			continue
+		}
+
+		var ok bool
+		if typ, ok = mergeColumnType(typ, valueType(row[col])); !ok {
+			return ""
+		}
+	}
+	return typ
+}
+
+// mergeColumnType combines the type of a column so far with the type of its
+// next non-nil value. It reports false when the column cannot be typed.
+func mergeColumnType(typ, next string) (string, bool) {
+	switch {
+	case next == "":
+		return "", false
+	case typ == "" || typ == next:
+		return next, true
+	case isNumericType(typ) && isNumericType(next):
+		return floatColumnType, true
+	default:
+		return "", false
+	}
+}
+
+// valueType returns the column type of a scalar value, or "" if it has none.
+func valueType(v any) string {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return intColumnType
+	case float32, float64:
+		return floatColumnType
+	case string:
+		return strColumnType
+	case bool:
+		return boolColumnType
+	case time.Time:
+		return timeColumnType
+	default:
+		return ""
+	}
+}
+
+// isNumericType checks if typ is int or float.
+func isNumericType(typ string) bool {
+	return typ == intColumnType || typ == floatColumnType
+}
+
+// isScalarColumnType checks if typ is one of the typed header column types.
+func isScalarColumnType(typ string) bool {
+	switch typ {
+	case intColumnType, floatColumnType, strColumnType, boolColumnType, timeColumnType:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseTyped converts raw cell text to the given column type. Empty cells and
+// "null" decode to nil. Cells that don't match the type are parsed as untyped
+// cells and returned along with an error.
+func (d *Decoder) parseTyped(raw, typ string) (any, error) {
+	text := strings.TrimSpace(raw)
+	if text == "" || text == "null" {
+		return nil, nil
+	}
+
+	var value any
+	var err error
+	switch typ {
+	case intColumnType:
+		var i int64
+		i, err = strconv.ParseInt(text, 10, 64)
+		value = int(i)
+	case floatColumnType:
+		value, err = strconv.ParseFloat(text, 64)
+	case strColumnType:
+		value = unescapeCell(text)
+	case boolColumnType:
+		switch text {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		default:
+			err = fmt.Errorf("not a boolean")
+		}
+	case timeColumnType:
+		layout := time.RFC3339
+		if d.config.DateFormat != "" {
+			layout = d.config.DateFormat
+		}
+		value, err = time.Parse(layout, unescapeCell(text))
+	default:
+		return d.parseCell(raw), nil
+	}
+
+	if err != nil {
+		return d.parseCell(raw), fmt.Errorf("invalid %s value %q", typ, text)
+	}
+	return value, nil
+}
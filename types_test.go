@@ -0,0 +1,162 @@
+package gotoon
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeTypedHeaders(t *testing.T) {
+	config := DefaultConfig()
+	config.TypedHeaders = true
+
+	at := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	data := []any{
+		map[string]any{"id": 1, "price": 10.0, "sku": "00123", "active": true, "at": at, "note": nil},
+		map[string]any{"id": 2, "price": 12.5, "sku": "A-7", "active": false, "at": at, "note": nil},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	header := "items[2]{active:bool,at:time,id:int,note,price:float,sku:str}:"
+	if !strings.HasPrefix(toon, header) {
+		t.Errorf("Expected header %q, got:\n%s", header, toon)
+	}
+
+	decoded, err := NewDecoder(config).Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	first := decoded["items"].([]any)[0].(map[string]any)
+	if price, ok := first["price"].(float64); !ok || price != 10 {
+		t.Errorf("Expected price to decode as float64 10, got %T %v", first["price"], first["price"])
+	}
+	if first["sku"] != "00123" {
+		t.Errorf("Expected sku to stay a string, got %T %v", first["sku"], first["sku"])
+	}
+	if decodedAt, ok := first["at"].(time.Time); !ok || !decodedAt.Equal(at) {
+		t.Errorf("Expected at to decode as time.Time, got %T %v", first["at"], first["at"])
+	}
+	if first["active"] != true || first["id"] != 1 || first["note"] != nil {
+		t.Errorf("Unexpected decoded row: %+v", first)
+	}
+}
+
+func TestTypedHeadersMixedNumbersAreFloat(t *testing.T) {
+	rows := [][]any{{1}, {2.5}, {nil}}
+	if typ := columnType(rows, 0); typ != floatColumnType {
+		t.Errorf("Expected float, got %q", typ)
+	}
+
+	rows = [][]any{{1}, {"two"}}
+	if typ := columnType(rows, 0); typ != "" {
+		t.Errorf("Expected mixed column to stay untyped, got %q", typ)
+	}
+}
+
+func TestTypedHeadersWithOtherOptions(t *testing.T) {
+	config := DefaultConfig()
+	config.TypedHeaders = true
+	config.DictionaryEncoding = true
+	config.HoistConstantColumns = true
+	config.SparseTables = true
+
+	data := map[string]any{
+		"orders": []any{
+			map[string]any{"id": 1, "status": "processing", "currency": "EUR", "total": 10.0},
+			map[string]any{"id": 2, "status": "processing", "currency": "EUR", "total": 7.5},
+			map[string]any{"id": 3, "status": "delivered", "currency": "EUR"},
+			map[string]any{"id": 4, "status": "processing", "currency": "EUR", "total": 3.0},
+		},
+	}
+
+	toon, err := NewEncoder(config).Encode(data)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	for _, want := range []string{"currency:str=EUR", "status:str", "total?:float", "&status: "} {
+		if !strings.Contains(toon, want) {
+			t.Errorf("Expected %q in output:\n%s", want, toon)
+		}
+	}
+
+	config.Strict = true
+	decoded, err := NewDecoder(config).Decode(toon)
+	if err != nil {
+		t.Fatalf("Decode failed: %v\n%s", err, toon)
+	}
+
+	if !reflect.DeepEqual(decoded, data) {
+		t.Errorf("Round trip mismatch.\nExpected: %+v\nGot:      %+v", data, decoded)
+	}
+}
+
+func TestStrictDecodeRejectsMistypedCell(t *testing.T) {
+	_, err := strictDecoder().Decode("items[2]{id:int,name:str}:\n  1,Alice\n  x,Bob")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Line != 3 || syntaxErr.Column != 3 {
+		t.Fatalf("Expected syntax error at 3:3, got: %v", err)
+	}
+
+	decoded, err := Decode("items[2]{id:int,name:str}:\n  1,Alice\n  x,Bob")
+	if err != nil {
+		t.Fatalf("Expected lenient decode to succeed, got: %v", err)
+	}
+	if id := decoded["items"].([]any)[1].(map[string]any)["id"]; id != "x" {
+		t.Errorf("Expected lenient decode to fall back to the cell text, got %v", id)
+	}
+}
+
+func TestDecodeTypeHints(t *testing.T) {
+	config := DefaultConfig()
+	config.TypeHints = map[string]string{
+		"user.zip":       "str",
+		"user.score":     "float",
+		"orders.*.total": "float",
+		"orders.*.code":  "str",
+		"tags.*":         "str",
+		"items.*.id":     "str",
+	}
+	decoder := NewDecoder(config)
+
+	decoded, err := decoder.Decode(`orders:
+  items[2]{code,total}@{currency=EUR}:
+    007,10
+    008,abc
+tags:
+  - 1
+  - true
+user:
+  score: 3
+  zip: 01234`)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	orders := decoded["orders"].([]any)
+	if first := orders[0].(map[string]any); first["code"] != "007" || first["total"] != 10.0 {
+		t.Errorf("Expected hinted column types, got %+v", first)
+	}
+	if second := orders[1].(map[string]any); second["total"] != "abc" {
+		t.Errorf("Expected mismatching cell to decode as usual, got %+v", second)
+	}
+	if !reflect.DeepEqual(decoded["tags"], []any{"1", "true"}) {
+		t.Errorf("Expected hinted list items, got %+v", decoded["tags"])
+	}
+	if user := decoded["user"].(map[string]any); user["zip"] != "01234" || user["score"] != 3.0 {
+		t.Errorf("Expected hinted values, got %+v", user)
+	}
+
+	top, _ := decoder.Decode("items[2]{id}:\n  1\n  2")
+	if id := top["items"].([]any)[0].(map[string]any)["id"]; id != "1" {
+		t.Errorf("Expected top-level table hint under items, got %T %v", id, id)
+	}
+}
@@ -21,6 +21,33 @@ func (u *ArrayUnflattener) Unflatten(rows [][]any, columns []string) []map[strin
 	return result
 }
 
+// Join replaces the reference column for key in each object with a copy of the
+// referenced item from table. Objects without a valid reference get a nil value.
+func (u *ArrayUnflattener) Join(objects []any, key string, table []any) {
+	column := referencePrefix + key
+
+	for _, item := range objects {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		ref, exists := obj[column]
+		if !exists {
+			continue
+		}
+		delete(obj, column)
+
+		idx, ok := ref.(int)
+		if !ok || idx < 0 || idx >= len(table) {
+			obj[key] = nil
+			continue
+		}
+
+		obj[key] = deepCopy(table[idx])
+	}
+}
+
 // unflattenRow converts a single flat row into a nested object.
 func (u *ArrayUnflattener) unflattenRow(row []any, columns []string) map[string]any {
 	item := make(map[string]any)
@@ -59,3 +86,23 @@ func (u *ArrayUnflattener) setByPath(data map[string]any, path string, value any
 		}
 	}
 }
+
+// deepCopy copies nested maps and slices so joined objects do not share state.
+func deepCopy(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(val))
+		for k, item := range val {
+			result[k] = deepCopy(item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(val))
+		for i, item := range val {
+			result[i] = deepCopy(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,428 @@
+package gotoon
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Unmarshal decodes toon into the value pointed to by v using the default
+// decoder. See Decoder.Unmarshal.
+func Unmarshal(toon string, v any) error {
+	return defaultDecoder.Unmarshal(toon, v)
+}
+
+// Unmarshal decodes toon into the value pointed to by v. Struct fields are
+// matched by their toon tag, then their json tag, then their name, ignoring
+// case as encoding/json does; keys without a field are ignored. A top-level
+// table or list is stored when v points to a slice or array.
+//
+// Values implementing ToonUnmarshaler receive the decoded value. Otherwise
+// encoding.TextUnmarshaler receives scalars as text and json.Unmarshaler
+// receives the value as JSON. Values bound for string, number and boolean
+// fields are decoded as that type, as if declared in TypeHints, so "02134"
+// stays a string; configured TypeHints take precedence.
+func (d *Decoder) Unmarshal(toon string, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("gotoon: Unmarshal needs a non-nil pointer, got %T", v)
+	}
+
+	decoded, err := d.withTypeHints(rv.Type().Elem()).Decode(toon)
+	if err != nil {
+		return err
+	}
+
+	var value any = decoded
+	target := rv.Type().Elem()
+	for target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	if kind := target.Kind(); kind == reflect.Slice || kind == reflect.Array {
+		if items, ok := decoded["items"]; ok && len(decoded) == 1 {
+			value = items
+		}
+	}
+
+	return unmarshalValue(rv.Elem(), value, "")
+}
+
+// withTypeHints returns a decoder whose TypeHints also cover the scalar
+// fields of t, or d when t has none.
+func (d *Decoder) withTypeHints(t reflect.Type) *Decoder {
+	hints := cachedTypeHints(t)
+	if len(hints) == 0 {
+		return d
+	}
+
+	config := *d.config
+	config.TypeHints = make(map[string]string, len(hints)+len(d.config.TypeHints))
+	for path, typ := range hints {
+		config.TypeHints[path] = typ
+	}
+	for path, typ := range d.config.TypeHints {
+		config.TypeHints[path] = typ
+	}
+	return NewDecoder(&config)
+}
+
+var typeHintCache sync.Map
+
+// cachedTypeHints returns the type hints for decoding into t. A slice or
+// array is addressed as a top-level table or list.
+func cachedTypeHints(t reflect.Type) map[string]string {
+	if hints, ok := typeHintCache.Load(t); ok {
+		return hints.(map[string]string)
+	}
+
+	target := t
+	for target.Kind() == reflect.Pointer {
+		target = target.Elem()
+	}
+	path := ""
+	if target.Kind() == reflect.Slice || target.Kind() == reflect.Array {
+		path = "items"
+	}
+	hints := make(map[string]string)
+	collectTypeHints(target, path, hints, map[reflect.Type]bool{})
+
+	stored, _ := typeHintCache.LoadOrStore(t, hints)
+	return stored.(map[string]string)
+}
+
+// collectTypeHints adds the column type of each scalar value of t, found at
+// path, to hints. Map values are addressed by "*". encoding.TextUnmarshaler
+// types other than time.Time read text; other types that unmarshal
+// themselves and interfaces are left to the decoder.
+func collectTypeHints(t reflect.Type, path string, hints map[string]string, visiting map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if visiting[t] || t == timeType {
+		return
+	}
+	switch reflect.New(t).Interface().(type) {
+	case ToonUnmarshaler:
+		return
+	case encoding.TextUnmarshaler:
+		hints[path] = strColumnType
+		return
+	case json.Unmarshaler:
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		hints[path] = strColumnType
+	case reflect.Bool:
+		hints[path] = boolColumnType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		hints[path] = intColumnType
+	case reflect.Float32, reflect.Float64:
+		hints[path] = floatColumnType
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			hints[path] = strColumnType
+			return
+		}
+		collectTypeHints(t.Elem(), joinPath(path, "*"), hints, visiting)
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			collectTypeHints(t.Elem(), joinPath(path, "*"), hints, visiting)
+		}
+	case reflect.Struct:
+		visiting[t] = true
+		for _, field := range cachedStructFields(t) {
+			collectTypeHints(t.FieldByIndex(field.index).Type, joinPath(path, field.name), hints, visiting)
+		}
+		delete(visiting, t)
+	}
+}
+
+// isUnmarshaler reports whether values of t unmarshal themselves through
+// ToonUnmarshaler, encoding.TextUnmarshaler or json.Unmarshaler.
+func isUnmarshaler(t reflect.Type) bool {
+	switch reflect.New(t).Interface().(type) {
+	case ToonUnmarshaler, encoding.TextUnmarshaler, json.Unmarshaler:
+		return true
+	}
+	return false
+}
+
+// unmarshalValue stores value in dst.
+func unmarshalValue(dst reflect.Value, value any, path string) error {
+	if handled, err := callUnmarshaler(dst, value, path); handled {
+		return err
+	}
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		if value == nil {
+			dst.SetZero()
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return unmarshalValue(dst.Elem(), value, path)
+
+	case reflect.Interface:
+		if value == nil {
+			dst.SetZero()
+			return nil
+		}
+		rv := reflect.ValueOf(value)
+		if !rv.Type().AssignableTo(dst.Type()) {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		dst.Set(rv)
+		return nil
+	}
+
+	if value == nil {
+		dst.SetZero()
+		return nil
+	}
+
+	if dst.Type() == timeType {
+		if t, ok := value.(time.Time); ok {
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		val, ok := value.(string)
+		if !ok {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		dst.SetString(val)
+
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		dst.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := integerValue(value)
+		if !ok || dst.OverflowInt(n) {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		dst.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := integerValue(value)
+		if !ok || n < 0 || dst.OverflowUint(uint64(n)) {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		dst.SetUint(uint64(n))
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch val := value.(type) {
+		case int:
+			f = float64(val)
+		case float64:
+			f = val
+		default:
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		if dst.OverflowFloat(f) {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		dst.SetFloat(f)
+
+	case reflect.Struct:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		fields := cachedStructFields(dst.Type())
+		for _, key := range sortedKeys(obj) {
+			field, ok := findField(fields, key)
+			if !ok {
+				continue
+			}
+			fv, ok := allocField(dst, field.index)
+			if !ok {
+				continue
+			}
+			if err := unmarshalValue(fv, obj[key], joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		obj, ok := value.(map[string]any)
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(obj)))
+		}
+		for _, key := range sortedKeys(obj) {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := unmarshalValue(elem, obj[key], joinPath(path, key)); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+		}
+
+	case reflect.Slice:
+		if s, ok := value.(string); ok && dst.Type().Elem().Kind() == reflect.Uint8 {
+			data, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return fmt.Errorf("%s: %w", pathOrRoot(path), err)
+			}
+			dst.SetBytes(data)
+			return nil
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := unmarshalValue(slice.Index(i), item, joinPath(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+
+	case reflect.Array:
+		items, ok := value.([]any)
+		if !ok || len(items) > dst.Len() {
+			return unmarshalTypeError(path, value, dst.Type())
+		}
+		dst.SetZero()
+		for i, item := range items {
+			if err := unmarshalValue(dst.Index(i), item, joinPath(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return unmarshalTypeError(path, value, dst.Type())
+	}
+	return nil
+}
+
+// callUnmarshaler stores value through ToonUnmarshaler, TextUnmarshaler or
+// json.Unmarshaler when dst implements one, and reports whether it did.
+func callUnmarshaler(dst reflect.Value, value any, path string) (bool, error) {
+	if dst.Kind() == reflect.Pointer || !dst.CanAddr() {
+		return false, nil
+	}
+
+	wrap := func(err error) error {
+		if err != nil {
+			return fmt.Errorf("%s: unmarshaling %s: %w", pathOrRoot(path), dst.Type(), err)
+		}
+		return nil
+	}
+
+	switch u := dst.Addr().Interface().(type) {
+	case ToonUnmarshaler:
+		return true, wrap(u.UnmarshalTOON(value))
+
+	case encoding.TextUnmarshaler:
+		if dst.Type() == timeType {
+			if _, ok := value.(time.Time); ok {
+				return false, nil
+			}
+		}
+		switch value.(type) {
+		case nil:
+			dst.SetZero()
+			return true, nil
+		case string, bool, int, float64, time.Time:
+			return true, wrap(u.UnmarshalText([]byte(scalarText(value))))
+		}
+		return true, unmarshalTypeError(path, value, dst.Type())
+
+	case json.Unmarshaler:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return true, wrap(err)
+		}
+		return true, wrap(u.UnmarshalJSON(data))
+	}
+	return false, nil
+}
+
+// findField returns the field named key, preferring an exact match over a
+// case-insensitive one.
+func findField(fields []structField, key string) (structField, bool) {
+	for _, field := range fields {
+		if field.name == key {
+			return field, true
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field.name, key) {
+			return field, true
+		}
+	}
+	return structField{}, false
+}
+
+// allocField returns the field at index, allocating nil embedded pointers on
+// the way. It reports false when such a pointer is unexported and cannot be
+// set.
+func allocField(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, false
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// integerValue returns value as an integer when it is one.
+func integerValue(value any) (int64, bool) {
+	switch val := value.(type) {
+	case int:
+		return int64(val), true
+	case float64:
+		if val == math.Trunc(val) && val >= math.MinInt64 && val < math.MaxInt64 {
+			return int64(val), true
+		}
+	}
+	return 0, false
+}
+
+// scalarText writes a decoded scalar as text.
+func scalarText(value any) string {
+	switch val := value.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// unmarshalTypeError reports a value that cannot be stored in typ.
+func unmarshalTypeError(path string, value any, typ reflect.Type) error {
+	return fmt.Errorf("%s: cannot store %T in %s", pathOrRoot(path), value, typ)
+}
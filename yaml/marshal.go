@@ -0,0 +1,234 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal writes v as a block-style YAML document. It accepts the values
+// Parse and the TOON decoder produce: map[string]any, []any, strings, bools,
+// integers, floats, time.Time and nil. Mapping keys are sorted, multi-line
+// strings become literal block strings, and strings that would read back as
+// another type are quoted.
+func Marshal(v any) ([]byte, error) {
+	var b strings.Builder
+	if err := writeBlock(&b, v, 0); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// writeBlock writes v as a block node whose lines are indented by indent.
+func writeBlock(b *strings.Builder, v any, indent int) error {
+	pad := strings.Repeat(" ", indent)
+
+	switch val := v.(type) {
+	case map[string]any:
+		if len(val) == 0 {
+			b.WriteString(pad + "{}\n")
+			return nil
+		}
+
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			b.WriteString(pad + quoteIfNeeded(key) + ":")
+			if err := writeValue(b, val[key], indent); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
+		return nil
+
+	case []any:
+		if len(val) == 0 {
+			b.WriteString(pad + "[]\n")
+			return nil
+		}
+
+		for i, item := range val {
+			if !isCollection(item) {
+				b.WriteString(pad + "-")
+				if err := writeValue(b, item, indent); err != nil {
+					return fmt.Errorf("[%d]: %w", i, err)
+				}
+				continue
+			}
+
+			// Write the item one level deeper, then put its first line
+			// after the dash.
+			var child strings.Builder
+			if err := writeBlock(&child, item, indent+2); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+			b.WriteString(pad + "- " + child.String()[indent+2:])
+		}
+		return nil
+	}
+
+	scalar, err := formatScalar(v)
+	if err != nil {
+		return err
+	}
+	b.WriteString(pad + scalar + "\n")
+	return nil
+}
+
+// writeValue writes v after a "key:" or "-" written at indent.
+func writeValue(b *strings.Builder, v any, indent int) error {
+	switch val := v.(type) {
+	case map[string]any:
+		if len(val) == 0 {
+			b.WriteString(" {}\n")
+			return nil
+		}
+		b.WriteString("\n")
+		return writeBlock(b, val, indent+2)
+	case []any:
+		if len(val) == 0 {
+			b.WriteString(" []\n")
+			return nil
+		}
+		b.WriteString("\n")
+		return writeBlock(b, val, indent+2)
+	case string:
+		if isBlockString(val) {
+			writeBlockString(b, val, indent+2)
+			return nil
+		}
+	}
+
+	scalar, err := formatScalar(v)
+	if err != nil {
+		return err
+	}
+	b.WriteString(" " + scalar + "\n")
+	return nil
+}
+
+// writeBlockString writes a multi-line string as a literal block string with
+// the chomping indicator that preserves its trailing newlines.
+func writeBlockString(b *strings.Builder, s string, indent int) {
+	body := strings.TrimRight(s, "\n")
+	switch trailing := len(s) - len(body); {
+	case trailing == 0:
+		b.WriteString(" |-\n")
+	case trailing == 1:
+		b.WriteString(" |\n")
+	default:
+		b.WriteString(" |+\n")
+	}
+
+	pad := strings.Repeat(" ", indent)
+	for _, line := range strings.Split(body, "\n") {
+		if line == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(pad + line + "\n")
+	}
+	for i := 1; i < len(s)-len(body); i++ {
+		b.WriteString("\n")
+	}
+}
+
+// isBlockString reports whether s can be written as a literal block string:
+// it has a line break before its trailing newlines, does not start with a
+// space or line break that would change the detected indentation, and has no
+// other control characters.
+func isBlockString(s string) bool {
+	if !strings.Contains(strings.TrimRight(s, "\n"), "\n") || strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\n") {
+		return false
+	}
+	for _, r := range s {
+		if r < ' ' && r != '\n' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// isCollection reports whether v is a non-empty mapping or sequence.
+func isCollection(v any) bool {
+	switch val := v.(type) {
+	case map[string]any:
+		return len(val) > 0
+	case []any:
+		return len(val) > 0
+	}
+	return false
+}
+
+// formatScalar formats a scalar for a plain or quoted position.
+func formatScalar(v any) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return quoteIfNeeded(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case int:
+		return strconv.Itoa(val), nil
+	case int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", val), nil
+	case float32:
+		return formatFloat(float64(val), 32), nil
+	case float64:
+		return formatFloat(val, 64), nil
+	case time.Time:
+		return val.Format(time.RFC3339Nano), nil
+	}
+	return "", fmt.Errorf("unsupported type %T", v)
+}
+
+// formatFloat formats f so it reads back as the same number.
+func formatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// quoteIfNeeded returns s as a plain scalar, or double-quoted when a plain
+// scalar would read back as another type or another string.
+func quoteIfNeeded(s string) string {
+	if needsQuotes(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// needsQuotes reports whether s cannot be written as a plain scalar.
+func needsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	if _, ok := resolvePlain(s).(string); !ok {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.HasSuffix(s, ":") || strings.Contains(s, ": ") || strings.Contains(s, " #") {
+		return true
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
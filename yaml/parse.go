@@ -0,0 +1,596 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/b92c/gotoon"
+)
+
+var (
+	intPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	floatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// Parse parses a YAML document into maps, []any, strings, int, float64, bool
+// and nil. It supports the block subset described in the package doc and
+// returns a *gotoon.SyntaxError for anything else.
+func Parse(data []byte) (any, error) {
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	p := &parser{lines: strings.Split(text, "\n")}
+
+	// Skip directives and the document start marker.
+	for i := 0; i < len(p.lines); i++ {
+		trimmed := strings.TrimSpace(p.lines[i])
+		if strings.HasPrefix(trimmed, "%") {
+			p.lines[i] = ""
+			continue
+		}
+		if trimmed == "---" {
+			p.lines[i] = ""
+			break
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+	}
+
+	line, ok := p.next()
+	if !ok {
+		return nil, nil
+	}
+
+	value, err := p.parseNode(lineIndent(p.lines[line]))
+	if err != nil {
+		return nil, err
+	}
+
+	if line, ok := p.next(); ok {
+		trimmed := strings.TrimSpace(p.lines[line])
+		if trimmed == "..." {
+			p.pos = line + 1
+			if _, more := p.next(); !more {
+				return value, nil
+			}
+		}
+		if trimmed == "---" {
+			return nil, p.fail(line, 0, "multiple documents are not supported")
+		}
+		return nil, p.fail(line, lineIndent(p.lines[line]), "unexpected content")
+	}
+	return value, nil
+}
+
+// parser reads YAML line by line.
+type parser struct {
+	lines []string
+	pos   int
+}
+
+// fail returns a syntax error at a 0-based line and column.
+func (p *parser) fail(line, column int, format string, args ...any) error {
+	return &gotoon.SyntaxError{Line: line + 1, Column: column + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next moves past blank and comment lines and returns the index of the next
+// content line.
+func (p *parser) next() (int, bool) {
+	for p.pos < len(p.lines) {
+		trimmed := strings.TrimSpace(p.lines[p.pos])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return p.pos, true
+		}
+		p.pos++
+	}
+	return 0, false
+}
+
+// checkTabs reports tabs used as indentation on line.
+func (p *parser) checkTabs(line int) error {
+	text := p.lines[line]
+	if strings.HasPrefix(text[lineIndent(text):], "\t") {
+		return p.fail(line, 0, "tabs are not allowed in indentation")
+	}
+	return nil
+}
+
+// parseNode parses the block node starting at the next content line, which
+// is indented by indent.
+func (p *parser) parseNode(indent int) (any, error) {
+	line, _ := p.next()
+	text := p.lines[line]
+	if err := p.checkTabs(line); err != nil {
+		return nil, err
+	}
+
+	content := stripComment(text[indent:])
+	switch {
+	case isSequenceItem(content):
+		return p.parseSequence(indent)
+	case isMappingLine(content):
+		return p.parseMapping(indent)
+	}
+
+	p.pos++
+	if content != "" && (content[0] == '|' || content[0] == '>') {
+		return p.parseBlockScalar(content, line, indent, indent-1)
+	}
+	return p.parseInline(content, line, indent)
+}
+
+// parseSequence parses block sequence items at indent.
+func (p *parser) parseSequence(indent int) (any, error) {
+	items := []any{}
+
+	for {
+		line, ok := p.next()
+		if !ok {
+			break
+		}
+		text := p.lines[line]
+		lineInd := lineIndent(text)
+		if lineInd < indent {
+			break
+		}
+		if lineInd > indent {
+			return nil, p.fail(line, lineInd, "unexpected indentation")
+		}
+		if err := p.checkTabs(line); err != nil {
+			return nil, err
+		}
+		if !isSequenceItem(stripComment(text[indent:])) {
+			break
+		}
+
+		rest := text[indent+1:]
+		column := indent + 1 + lineIndent(rest)
+		if strings.TrimSpace(stripComment(rest)) == "" {
+			p.pos++
+			item, err := p.parseChild(indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		// Parse the item's content as if the dash were indentation, so
+		// "- key: value" starts a mapping at the column of "key".
+		p.lines[line] = strings.Repeat(" ", column) + text[column:]
+		item, err := p.parseNode(column)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// parseMapping parses block mapping entries at indent.
+func (p *parser) parseMapping(indent int) (any, error) {
+	obj := map[string]any{}
+
+	for {
+		line, ok := p.next()
+		if !ok {
+			break
+		}
+		text := p.lines[line]
+		lineInd := lineIndent(text)
+		if lineInd < indent {
+			break
+		}
+		if lineInd > indent {
+			return nil, p.fail(line, lineInd, "unexpected indentation")
+		}
+		if err := p.checkTabs(line); err != nil {
+			return nil, err
+		}
+
+		content := stripComment(text[indent:])
+		if !isMappingLine(content) {
+			if isSequenceItem(content) || content == "---" || content == "..." {
+				break
+			}
+			return nil, p.fail(line, indent, "expected a key")
+		}
+
+		key, rest, err := splitMappingLine(content)
+		if err != nil {
+			return nil, p.fail(line, indent, "%v", err)
+		}
+		if _, exists := obj[key]; exists {
+			return nil, p.fail(line, indent, "duplicate key %q", key)
+		}
+		p.pos++
+
+		column := indent + len(content) - len(rest)
+		var value any
+		switch {
+		case rest == "":
+			value, err = p.parseChild(indent)
+		case rest[0] == '|' || rest[0] == '>':
+			value, err = p.parseBlockScalar(rest, line, column, indent)
+		default:
+			value, err = p.parseInline(rest, line, column)
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = value
+	}
+
+	return obj, nil
+}
+
+// parseChild parses the value of a key or dash with nothing after it: a
+// block indented deeper than parent, a sequence at the same indent under a
+// key, or nil.
+func (p *parser) parseChild(parent int) (any, error) {
+	line, ok := p.next()
+	if !ok {
+		return nil, nil
+	}
+
+	text := p.lines[line]
+	lineInd := lineIndent(text)
+	switch {
+	case lineInd > parent:
+		return p.parseNode(lineInd)
+	case lineInd == parent && isSequenceItem(stripComment(text[lineInd:])):
+		// "key:" followed by "- item" at the key's indent.
+		return p.parseSequence(parent)
+	default:
+		return nil, nil
+	}
+}
+
+// parseInline parses a value written on one line: a flow collection or a
+// scalar.
+func (p *parser) parseInline(text string, line, column int) (any, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	switch text[0] {
+	case '[', '{':
+		value, rest, err := parseFlow(text)
+		if err != nil {
+			return nil, p.fail(line, column, "%v", err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, p.fail(line, column, "unexpected %q after flow collection", rest)
+		}
+		return value, nil
+	case '&', '*', '!', '?':
+		return nil, p.fail(line, column, "anchors, aliases, tags and complex keys are not supported")
+	case '"', '\'':
+		value, rest, err := parseQuoted(text)
+		if err != nil {
+			return nil, p.fail(line, column, "%v", err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, p.fail(line, column, "unexpected %q after quoted string", rest)
+		}
+		return value, nil
+	}
+
+	return resolvePlain(text), nil
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) block string whose
+// header is on line and whose content is indented deeper than parent.
+func (p *parser) parseBlockScalar(header string, line, column, parent int) (any, error) {
+	style := header[0]
+	chomp := byte(0)
+	for _, c := range []byte(strings.TrimSpace(header[1:])) {
+		switch c {
+		case '-', '+':
+			chomp = c
+		default:
+			return nil, p.fail(line, column, "unsupported block scalar header %q", header)
+		}
+	}
+
+	var lines []string
+	contentIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		text := p.lines[p.pos]
+		if strings.TrimSpace(text) == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		ind := lineIndent(text)
+		if contentIndent < 0 {
+			if ind <= parent {
+				break
+			}
+			contentIndent = ind
+		}
+		if ind < contentIndent {
+			break
+		}
+		lines = append(lines, text[contentIndent:])
+	}
+
+	// Trailing blank lines belong to the block only for chomping.
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	var text string
+	if style == '|' {
+		text = strings.Join(lines, "\n")
+	} else {
+		text = foldLines(lines)
+	}
+
+	switch chomp {
+	case '-':
+		return text, nil
+	case '+':
+		return text + strings.Repeat("\n", trailing+1), nil
+	default:
+		return text + "\n", nil
+	}
+}
+
+// foldLines joins the lines of a folded block string: lines are joined with a
+// space, blank lines become line breaks, and more-indented lines are kept as
+// they are.
+func foldLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case line == "" || prev == "":
+				if prev != "" {
+					b.WriteByte('\n')
+				}
+				if line == "" {
+					continue
+				}
+			case strings.HasPrefix(line, " ") || strings.HasPrefix(prev, " "):
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(' ')
+			}
+		}
+		if line == "" {
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// parseFlow parses a flow sequence or mapping at the start of text and
+// returns the rest of the text.
+func parseFlow(text string) (any, string, error) {
+	text = strings.TrimLeft(text, " ")
+	if text == "" {
+		return nil, "", fmt.Errorf("unexpected end of flow collection")
+	}
+
+	switch text[0] {
+	case '[':
+		items := []any{}
+		rest := strings.TrimLeft(text[1:], " ")
+		for {
+			if rest == "" {
+				return nil, "", fmt.Errorf("multi-line flow collections are not supported")
+			}
+			if rest[0] == ']' {
+				return items, rest[1:], nil
+			}
+
+			item, after, err := parseFlow(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, item)
+
+			rest, err = flowSeparator(after, ']')
+			if err != nil {
+				return nil, "", err
+			}
+		}
+	case '{':
+		obj := map[string]any{}
+		rest := strings.TrimLeft(text[1:], " ")
+		for {
+			if rest == "" {
+				return nil, "", fmt.Errorf("multi-line flow collections are not supported")
+			}
+			if rest[0] == '}' {
+				return obj, rest[1:], nil
+			}
+
+			keyValue, after, err := parseFlow(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			key := fmt.Sprint(keyValue)
+			if keyValue == nil {
+				key = "null"
+			}
+
+			after = strings.TrimLeft(after, " ")
+			var value any
+			if strings.HasPrefix(after, ":") {
+				value, after, err = parseFlow(after[1:])
+				if err != nil {
+					return nil, "", err
+				}
+			}
+			obj[key] = value
+
+			rest, err = flowSeparator(after, '}')
+			if err != nil {
+				return nil, "", err
+			}
+		}
+	case '"', '\'':
+		return parseQuoted(text)
+	}
+
+	end := strings.IndexAny(text, ",]}")
+	if colon := strings.Index(text, ": "); colon >= 0 && (end < 0 || colon < end) {
+		end = colon
+	}
+	if end < 0 {
+		end = len(text)
+	}
+	return resolvePlain(strings.TrimSpace(text[:end])), text[end:], nil
+}
+
+// flowSeparator consumes the comma after a flow entry, leaving the closing
+// bracket in place.
+func flowSeparator(text string, closing byte) (string, error) {
+	text = strings.TrimLeft(text, " ")
+	switch {
+	case text == "":
+		return "", fmt.Errorf("multi-line flow collections are not supported")
+	case text[0] == ',':
+		return strings.TrimLeft(text[1:], " "), nil
+	case text[0] == closing:
+		return text, nil
+	default:
+		return "", fmt.Errorf("expected ',' or %q in flow collection", closing)
+	}
+}
+
+// parseQuoted parses a single- or double-quoted string at the start of text
+// and returns the rest of the text.
+func parseQuoted(text string) (string, string, error) {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			if quote == '\'' {
+				return strings.ReplaceAll(text[1:i], "''", "'"), text[i+1:], nil
+			}
+			value, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid double-quoted string %s", text[:i+1])
+			}
+			return value, text[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted string")
+}
+
+// resolvePlain converts a plain scalar to nil, a bool, a number or a string
+// following the YAML 1.2 core schema.
+func resolvePlain(text string) any {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	if intPattern.MatchString(text) {
+		if i, err := strconv.Atoi(text); err == nil {
+			return i
+		}
+	}
+	if floatPattern.MatchString(text) {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	}
+	return text
+}
+
+// isSequenceItem reports whether content starts a block sequence item.
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+// isMappingLine reports whether content is a "key: value" or "key:" line.
+func isMappingLine(content string) bool {
+	_, _, err := splitMappingLine(content)
+	return err == nil
+}
+
+// splitMappingLine splits a mapping line into its key and the trimmed text
+// after the colon.
+func splitMappingLine(content string) (string, string, error) {
+	if content == "" || strings.ContainsAny(content[:1], "[{#&*!|>%@`") || isSequenceItem(content) {
+		return "", "", fmt.Errorf("expected a key")
+	}
+
+	if content[0] == '"' || content[0] == '\'' {
+		key, rest, err := parseQuoted(content)
+		if err != nil {
+			return "", "", err
+		}
+		rest = strings.TrimLeft(rest, " ")
+		if rest != ":" && !strings.HasPrefix(rest, ": ") {
+			return "", "", fmt.Errorf("expected ':' after key")
+		}
+		return key, strings.TrimSpace(rest[1:]), nil
+	}
+
+	var key, rest string
+	if i := strings.Index(content, ": "); i >= 0 {
+		key, rest = content[:i], content[i+2:]
+	} else if strings.HasSuffix(content, ":") {
+		key = content[:len(content)-1]
+	} else {
+		return "", "", fmt.Errorf("expected a key")
+	}
+	return strings.TrimSpace(key), strings.TrimSpace(rest), nil
+}
+
+// stripComment removes a trailing "# comment" outside quotes.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" [{,:", rune(text[i-1]))):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+	return strings.TrimRight(text, " ")
+}
+
+// lineIndent returns the number of leading spaces of line.
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,41 @@
+// Package yaml converts between YAML and TOON without external dependencies.
+// It reads and writes the subset of YAML used by configs and fixtures: block
+// mappings and sequences, flow collections on a single line, plain and quoted
+// scalars, literal (|) and folded (>) block strings, and comments. Anchors,
+// aliases, tags, complex keys and multiple documents are reported as syntax
+// errors.
+//
+// Scalars follow the YAML 1.2 core schema: null and ~ are nil, true and false
+// are booleans, and numbers become int or float64. Everything else, including
+// timestamps, stays a string.
+package yaml
+
+import (
+	"github.com/b92c/gotoon"
+)
+
+// ToTOON converts a YAML document to TOON. Nil config uses
+// gotoon.DefaultConfig.
+func ToTOON(data []byte, config *gotoon.Config) (string, error) {
+	value, err := Parse(data)
+	if err != nil {
+		return "", err
+	}
+	return gotoon.NewEncoder(config).Encode(value)
+}
+
+// FromTOON converts a TOON document to YAML. A document holding only a
+// top-level table or list becomes a YAML sequence, so sequences survive a
+// round trip through ToTOON. Nil config uses gotoon.DefaultConfig.
+func FromTOON(toon string, config *gotoon.Config) ([]byte, error) {
+	decoded, err := gotoon.NewDecoder(config).Decode(toon)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any = decoded
+	if items, ok := decoded["items"].([]any); ok && len(decoded) == 1 {
+		value = items
+	}
+	return Marshal(value)
+}
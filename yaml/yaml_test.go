@@ -0,0 +1,239 @@
+package yaml
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/b92c/gotoon"
+)
+
+const fixture = `# Service fixture
+---
+name: billing
+version: 1.2
+replicas: 3
+enabled: true
+owner: ~
+zip: "01234"
+quote: 'it''s here'
+url: http://example.com/a#b # trailing comment
+tags: [api, "internal", 3]
+limits: {cpu: 500m, memory: 1Gi}
+empty:
+users:
+  - id: 1
+    name: Alice
+    roles:
+      - admin
+      - dev
+  - id: 2
+    name: Bob, Jr
+    roles: []
+matrix:
+- - 1
+  - 2
+- - 3
+script: |
+  echo one
+    echo two
+
+  echo three
+summary: >-
+  folded text
+  on two lines
+
+  new paragraph
+`
+
+func TestParse(t *testing.T) {
+	value, err := Parse([]byte(fixture))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := map[string]any{
+		"name":     "billing",
+		"version":  1.2,
+		"replicas": 3,
+		"enabled":  true,
+		"owner":    nil,
+		"zip":      "01234",
+		"quote":    "it's here",
+		"url":      "http://example.com/a#b",
+		"tags":     []any{"api", "internal", 3},
+		"limits":   map[string]any{"cpu": "500m", "memory": "1Gi"},
+		"empty":    nil,
+		"users": []any{
+			map[string]any{"id": 1, "name": "Alice", "roles": []any{"admin", "dev"}},
+			map[string]any{"id": 2, "name": "Bob, Jr", "roles": []any{}},
+		},
+		"matrix":  []any{[]any{1, 2}, []any{3}},
+		"script":  "echo one\n  echo two\n\necho three\n",
+		"summary": "folded text on two lines\nnew paragraph",
+	}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Unexpected value.\nExpected: %#v\nGot:      %#v", expected, value)
+	}
+}
+
+func TestParseTopLevelValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"", nil},
+		{"# only a comment\n", nil},
+		{"- a\n- b\n", []any{"a", "b"}},
+		{"hello world\n", "hello world"},
+		{"---\n-1.5e3\n...\n", -1500.0},
+	}
+
+	for _, test := range tests {
+		value, err := Parse([]byte(test.input))
+		if err != nil {
+			t.Errorf("%q: Parse failed: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("%q: expected %#v, got %#v", test.input, test.expected, value)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a: 1\n\tb: 2\n", "line 2, column 1: tabs are not allowed"},
+		{"a: 1\n  b: 2\n", "line 2, column 3: unexpected indentation"},
+		{"a: 1\na: 2\n", `line 2, column 1: duplicate key "a"`},
+		{"base: &base\n  a: 1\n", "line 1, column 7: anchors, aliases"},
+		{"a: [1, 2\n", "multi-line flow collections are not supported"},
+		{"a: 1\n---\nb: 2\n", "line 2, column 1: multiple documents"},
+		{"a: \"unterminated\n", "unterminated quoted string"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+
+		var syntaxErr *gotoon.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a SyntaxError, got %v", test.input, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: expected %q, got %q", test.input, test.want, err)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	value := map[string]any{
+		"name":    "billing",
+		"zip":     "01234",
+		"flag":    "true",
+		"note":    "key: value",
+		"empty":   "",
+		"owner":   nil,
+		"created": time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+		"limits":  map[string]any{},
+		"script":  "echo one\n  echo two\n",
+		"users": []any{
+			map[string]any{"id": 1, "roles": []any{"admin"}},
+			map[string]any{"id": 2, "roles": []any{}},
+		},
+		"matrix": []any{[]any{1, 2}, "three"},
+	}
+
+	data, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := `created: 2024-01-02T10:00:00Z
+empty: ""
+flag: "true"
+limits: {}
+matrix:
+  - - 1
+    - 2
+  - three
+name: billing
+note: "key: value"
+owner: null
+script: |
+  echo one
+    echo two
+users:
+  - id: 1
+    roles:
+      - admin
+  - id: 2
+    roles: []
+zip: "01234"
+`
+	if string(data) != expected {
+		t.Errorf("Unexpected YAML.\nExpected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	value["created"] = "2024-01-02T10:00:00Z"
+	if !reflect.DeepEqual(parsed, value) {
+		t.Errorf("Round trip changed the value.\nExpected: %#v\nGot:      %#v", value, parsed)
+	}
+}
+
+func TestMarshalBlockStringChomping(t *testing.T) {
+	for _, s := range []string{"a\nb", "a\nb\n", "a\n\nb\n\n\n"} {
+		data, err := Marshal(map[string]any{"text": s})
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		parsed, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Parse failed: %v\n%s", err, data)
+		}
+		if got := parsed.(map[string]any)["text"]; got != s {
+			t.Errorf("Expected %q back, got %q from:\n%s", s, got, data)
+		}
+	}
+}
+
+func TestToTOONAndFromTOON(t *testing.T) {
+	input := "- id: 1\n  name: Alice\n  active: true\n- id: 2\n  name: Bob\n  active: false\n"
+
+	toon, err := ToTOON([]byte(input), nil)
+	if err != nil {
+		t.Fatalf("ToTOON failed: %v", err)
+	}
+	expected := "items[2]{active,id,name}:\n  true,1,Alice\n  false,2,Bob"
+	if toon != expected {
+		t.Errorf("Unexpected TOON.\nExpected:\n%s\nGot:\n%s", expected, toon)
+	}
+
+	back, err := FromTOON(toon, nil)
+	if err != nil {
+		t.Fatalf("FromTOON failed: %v", err)
+	}
+	if string(back) != "- active: true\n  id: 1\n  name: Alice\n- active: false\n  id: 2\n  name: Bob\n" {
+		t.Errorf("Unexpected YAML:\n%s", back)
+	}
+}
+
+func TestFromTOONDocument(t *testing.T) {
+	back, err := FromTOON("service:\n  name: billing\n  ports:\n    - 80\n    - 443", nil)
+	if err != nil {
+		t.Fatalf("FromTOON failed: %v", err)
+	}
+	expected := "service:\n  name: billing\n  ports:\n    - 80\n    - 443\n"
+	if string(back) != expected {
+		t.Errorf("Unexpected YAML.\nExpected:\n%s\nGot:\n%s", expected, back)
+	}
+}